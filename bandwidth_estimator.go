@@ -0,0 +1,187 @@
+package gortsplib
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+)
+
+// BandwidthEstimate is a bandwidth estimate produced by a serverSessionMedia
+// for a sender that supports transport-wide congestion control feedback
+// (transport-cc, draft-holmer-rmcat-transport-wide-cc-extensions) or REMB.
+type BandwidthEstimate struct {
+	// BitrateBps is the current estimated available bitrate, in bits per second.
+	BitrateBps uint64
+
+	// LossFraction is the most recent fraction of packets reported lost,
+	// in the 0-1 range.
+	LossFraction float64
+}
+
+// OnBandwidthEstimateFunc is the prototype of the function passed to
+// ServerSession.OnBandwidthEstimate().
+type OnBandwidthEstimateFunc func(BandwidthEstimate)
+
+// bandwidthEstimator keeps a rolling estimate of the available bitrate
+// towards a single counterpart, combining a Kalman-style arrival-time
+// gradient estimator (fed by transport-cc feedback) with a loss-based
+// fallback (fed by REMB and RTCP receiver reports).
+type bandwidthEstimator struct {
+	mutex sync.Mutex
+
+	// gradient estimator state
+	gradientEstimate float64 // smoothed inter-arrival delay variation, in ms
+	gradientVariance float64
+	lastArrival      time.Time
+	lastDeparture    time.Time
+
+	// loss-based state
+	bitrate uint64 // bits per second
+	loss    float64
+
+	onEstimate OnBandwidthEstimateFunc
+}
+
+func newBandwidthEstimator() *bandwidthEstimator {
+	return &bandwidthEstimator{
+		gradientVariance: 1,
+		bitrate:          1_000_000, // start at 1 Mbit/s until feedback arrives
+		onEstimate:       func(BandwidthEstimate) {},
+	}
+}
+
+// processArrival feeds a single transport-cc packet-arrival sample
+// (departure time at the sender, arrival time reported by the receiver)
+// into the arrival-time gradient estimator.
+func (be *bandwidthEstimator) processArrival(departure, arrival time.Time, packetSize uint64) {
+	be.mutex.Lock()
+	defer be.mutex.Unlock()
+
+	if !be.lastArrival.IsZero() {
+		departureDelta := departure.Sub(be.lastDeparture).Seconds()
+		arrivalDelta := arrival.Sub(be.lastArrival).Seconds()
+
+		if departureDelta > 0 {
+			// inter-group delay variation: positive means the network queue is growing.
+			gradient := (arrivalDelta - departureDelta) * 1000 // ms
+
+			// Kalman update: gain shrinks as our variance estimate shrinks,
+			// so a noisy channel converges slower than a quiet one.
+			const processNoise = 1e-3
+			be.gradientVariance += processNoise
+			gain := be.gradientVariance / (be.gradientVariance + 10) // 10 == measurement noise
+			be.gradientEstimate += gain * (gradient - be.gradientEstimate)
+			be.gradientVariance *= (1 - gain)
+
+			switch {
+			case be.gradientEstimate > 0.5: // queue growing: back off
+				be.bitrate = be.bitrate * 9 / 10
+			case be.gradientEstimate < -0.5: // queue draining: headroom available
+				be.bitrate = be.bitrate + be.bitrate/20
+			}
+		}
+	}
+
+	be.lastDeparture = departure
+	be.lastArrival = arrival
+
+	be.onEstimate(BandwidthEstimate{BitrateBps: be.bitrate, LossFraction: be.loss})
+}
+
+// processLoss folds a fractional-loss sample (as carried by RTCP receiver
+// reports) into the estimate: the rate is halved above 10% loss, raised by
+// a twentieth of its current value below 2% loss (a proportional step, not
+// a fixed additive one), and held steady otherwise.
+func (be *bandwidthEstimator) processLoss(lossFraction float64) {
+	be.mutex.Lock()
+	defer be.mutex.Unlock()
+
+	be.loss = lossFraction
+
+	switch {
+	case lossFraction > 0.1:
+		be.bitrate /= 2
+	case lossFraction < 0.02:
+		be.bitrate += be.bitrate / 20
+	}
+
+	be.onEstimate(BandwidthEstimate{BitrateBps: be.bitrate, LossFraction: be.loss})
+}
+
+// processREMB clamps the estimate to a remote-suggested maximum, as carried
+// by a goog-remb RTCP packet.
+func (be *bandwidthEstimator) processREMB(maxBitrateBps uint64) {
+	be.mutex.Lock()
+	defer be.mutex.Unlock()
+
+	if be.bitrate > maxBitrateBps {
+		be.bitrate = maxBitrateBps
+	}
+
+	be.onEstimate(BandwidthEstimate{BitrateBps: be.bitrate, LossFraction: be.loss})
+}
+
+func (be *bandwidthEstimator) estimate() BandwidthEstimate {
+	be.mutex.Lock()
+	defer be.mutex.Unlock()
+	return BandwidthEstimate{BitrateBps: be.bitrate, LossFraction: be.loss}
+}
+
+// processBandwidthFeedback folds an incoming RTCP feedback packet into the
+// per-media bandwidth estimate, if it carries transport-cc or REMB
+// information. It is a no-op for any other packet type. TransportLayerCC
+// feedback only produces gradient samples if EnableTransportCC was called
+// on this media, since that's what records the real per-packet departure
+// times the arrivals below are paired against.
+func (sm *serverSessionMedia) processBandwidthFeedback(pkt rtcp.Packet) {
+	switch fb := pkt.(type) {
+	case *rtcp.TransportLayerCC:
+		// fb.PacketChunks maps each sequence number in
+		// [fb.BaseSequenceNumber, fb.BaseSequenceNumber+fb.PacketStatusCount)
+		// to received/not-received; fb.RecvDeltas then has exactly one entry
+		// per received packet, in the same order. Walk both in lockstep so
+		// each delta is paired with the sequence number it actually
+		// describes, instead of being applied to a single shared timestamp.
+		statuses := twccPacketStatuses(fb)
+
+		// RecvDeltas hold microseconds (250us ticks on the wire), each relative
+		// to the previous received packet, the first relative to fb.ReferenceTime.
+		arrival := time.Unix(0, int64(fb.ReferenceTime)*int64(twccReferenceUnit))
+		deltaIdx := 0
+		seq := fb.BaseSequenceNumber
+
+		for _, received := range statuses {
+			if received && deltaIdx < len(fb.RecvDeltas) {
+				delta := fb.RecvDeltas[deltaIdx]
+				deltaIdx++
+
+				arrival = arrival.Add(time.Duration(delta.Delta) * time.Microsecond)
+
+				if sm.twccSender != nil {
+					if departure, ok := sm.twccSender.departureFor(seq); ok {
+						sm.bwEstimator.processArrival(departure, arrival, 0)
+					}
+				}
+			}
+			seq++
+		}
+
+	case *rtcp.ReceiverEstimatedMaximumBitrate:
+		sm.bwEstimator.processREMB(uint64(fb.Bitrate))
+
+	case *rtcp.ReceiverReport:
+		for _, report := range fb.Reports {
+			sm.bwEstimator.processLoss(float64(report.FractionLost) / 256)
+		}
+
+	case *rtcp.SenderReport:
+		// no loss/arrival information carried directly; ignored here.
+	}
+}
+
+// BandwidthEstimate returns the current bandwidth estimate for this media,
+// derived from transport-cc and REMB feedback received from the counterpart.
+func (sm *serverSessionMedia) BandwidthEstimate() BandwidthEstimate {
+	return sm.bwEstimator.estimate()
+}
@@ -0,0 +1,290 @@
+package gortsplib
+
+import (
+	"encoding/binary"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// twccDeltaUnit is the tick size RTCP transport-wide-cc feedback expresses
+// receive deltas in, per draft-holmer-rmcat-transport-wide-cc-extensions.
+const twccDeltaUnit = 250 * time.Microsecond
+
+// twccReferenceUnit is the tick size of a TransportLayerCC's ReferenceTime
+// field.
+const twccReferenceUnit = 64 * time.Millisecond
+
+const twccCacheSize = 2048
+
+// twccDeparture is one entry of twccSender's departure-time ring buffer.
+// valid distinguishes a real seq 0 entry from an untouched zero-value
+// slot, since both would otherwise have seq == 0.
+type twccDeparture struct {
+	seq   uint16
+	time  time.Time
+	valid bool
+}
+
+// twccSender tags every outgoing RTP packet on a media with a transport-
+// wide sequence number, via the RTP header extension negotiated over SDP
+// (a=extmap for the transport-cc URI), and records when each one was
+// actually sent. This is what lets processBandwidthFeedback turn a
+// TransportLayerCC feedback packet into real per-packet departure/arrival
+// pairs instead of a single guessed timestamp for the whole packet.
+type twccSender struct {
+	sm          *serverSessionMedia
+	extensionID uint8
+
+	mutex      sync.Mutex
+	nextSeq    uint16
+	departures [twccCacheSize]twccDeparture
+}
+
+func newTWCCSender(sm *serverSessionMedia, extensionID uint8) *twccSender {
+	return &twccSender{sm: sm, extensionID: extensionID}
+}
+
+// writeInterceptor must be registered with AddRTPWriteInterceptor.
+func (s *twccSender) writeInterceptor(pkt *rtp.Packet) *rtp.Packet {
+	now := s.sm.ss.s.timeNow()
+
+	s.mutex.Lock()
+	seq := s.nextSeq
+	s.nextSeq++
+	s.departures[seq%twccCacheSize] = twccDeparture{seq: seq, time: now, valid: true}
+	s.mutex.Unlock()
+
+	buf := make([]byte, 2)
+	binary.BigEndian.PutUint16(buf, seq)
+	pkt.SetExtension(s.extensionID, buf) //nolint:errcheck
+
+	return pkt
+}
+
+// departureFor returns the departure time recorded for seq by
+// writeInterceptor, or false if it fell out of the ring buffer or was
+// never sent.
+func (s *twccSender) departureFor(seq uint16) (time.Time, bool) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	d := s.departures[seq%twccCacheSize]
+	if !d.valid || d.seq != seq {
+		return time.Time{}, false
+	}
+	return d.time, true
+}
+
+// EnableTransportCC makes writes on this media tag every outgoing RTP
+// packet with a transport-wide sequence number on extensionID (the RTP
+// header extension id negotiated via SDP a=extmap for the transport-cc
+// URI), and records a real departure time for each one so that feedback
+// received back through a TransportLayerCC packet can be resolved to an
+// actual per-packet send time by BandwidthEstimate's estimator.
+func (sm *serverSessionMedia) EnableTransportCC(extensionID uint8) {
+	s := newTWCCSender(sm, extensionID)
+	sm.twccSender = s
+	sm.AddRTPWriteInterceptor(s.writeInterceptor)
+}
+
+// twccFeedbackGenerator reads the transport-wide sequence number off
+// incoming RTP packets (as tagged by a counterpart's twccSender) and
+// periodically emits a rtcp.TransportLayerCC summarizing what arrived and
+// when, so the counterpart can build a bandwidth estimate from real
+// arrival times instead of loss alone.
+type twccFeedbackGenerator struct {
+	sm          *serverSessionMedia
+	extensionID uint8
+	interval    time.Duration
+
+	mutex    sync.Mutex
+	arrivals map[uint16]time.Time
+	fbCount  uint8
+
+	closed chan struct{}
+}
+
+func newTWCCFeedbackGenerator(sm *serverSessionMedia, extensionID uint8, interval time.Duration) *twccFeedbackGenerator {
+	if interval <= 0 {
+		interval = 100 * time.Millisecond
+	}
+	return &twccFeedbackGenerator{
+		sm:          sm,
+		extensionID: extensionID,
+		interval:    interval,
+		arrivals:    make(map[uint16]time.Time),
+		closed:      make(chan struct{}),
+	}
+}
+
+// readInterceptor must be registered with AddRTPReadInterceptor.
+func (g *twccFeedbackGenerator) readInterceptor(pkt *rtp.Packet) *rtp.Packet {
+	ext := pkt.GetExtension(g.extensionID)
+	if len(ext) >= 2 {
+		seq := binary.BigEndian.Uint16(ext)
+
+		g.mutex.Lock()
+		g.arrivals[seq] = g.sm.ss.s.timeNow()
+		g.mutex.Unlock()
+	}
+
+	return pkt
+}
+
+func (g *twccFeedbackGenerator) start() {
+	go g.run()
+}
+
+func (g *twccFeedbackGenerator) run() {
+	ticker := time.NewTicker(g.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-g.closed:
+			return
+		case <-ticker.C:
+			g.sendFeedback()
+		}
+	}
+}
+
+func (g *twccFeedbackGenerator) sendFeedback() {
+	g.mutex.Lock()
+	arrivals := g.arrivals
+	g.arrivals = make(map[uint16]time.Time)
+	fbCount := g.fbCount
+	g.fbCount++
+	g.mutex.Unlock()
+
+	if len(arrivals) == 0 {
+		return
+	}
+
+	sm := g.sm
+	sm.writePacketRTCP(buildTWCCFeedback(arrivals, fbCount)) //nolint:errcheck
+}
+
+func (g *twccFeedbackGenerator) close() {
+	close(g.closed)
+}
+
+// EnableTransportCCFeedback starts periodically emitting TransportLayerCC
+// feedback summarizing RTP packets received on this media, reading the
+// transport-wide sequence number from extensionID on each one (see
+// EnableTransportCC on the sending side). interval controls how often
+// feedback is sent; a typical value is 50-100ms. Feedback generation stops
+// when the session's media is stopped. Calling this again replaces and
+// stops any previously started feedback generator.
+func (sm *serverSessionMedia) EnableTransportCCFeedback(extensionID uint8, interval time.Duration) {
+	if sm.twccFeedback != nil {
+		sm.twccFeedback.close()
+	}
+
+	g := newTWCCFeedbackGenerator(sm, extensionID, interval)
+	sm.twccFeedback = g
+	sm.AddRTPReadInterceptor(g.readInterceptor)
+	g.start()
+}
+
+// buildTWCCFeedback assembles a rtcp.TransportLayerCC reporting exactly
+// the sequence numbers in arrivals as received, with every other sequence
+// in [min(arrivals), max(arrivals)] implicitly reported as not received,
+// and an individual receive delta for each received packet relative to
+// the previous one (or to the feedback's own reference time, for the
+// first).
+func buildTWCCFeedback(arrivals map[uint16]time.Time, fbPktCount uint8) *rtcp.TransportLayerCC {
+	seqs := make([]uint16, 0, len(arrivals))
+	for seq := range arrivals {
+		seqs = append(seqs, seq)
+	}
+	sort.Slice(seqs, func(i, j int) bool { return seqs[i] < seqs[j] })
+
+	base := seqs[0]
+	last := seqs[len(seqs)-1]
+	count := last - base + 1
+
+	reference := arrivals[base]
+	referenceTicks := reference.UnixNano() / int64(twccReferenceUnit)
+
+	var chunks []rtcp.PacketStatusChunk
+	var deltas []*rtcp.RecvDelta
+
+	var runSymbol rtcp.SymbolTypeTCC
+	var runLength uint16
+	flushRun := func() {
+		if runLength > 0 {
+			chunks = append(chunks, &rtcp.RunLengthChunk{PacketStatusSymbol: runSymbol, RunLength: runLength})
+		}
+	}
+
+	prevArrival := time.Unix(0, referenceTicks*int64(twccReferenceUnit))
+	for seq := base; ; seq++ {
+		symbol := rtcp.TypeTCCPacketNotReceived
+
+		if arrival, ok := arrivals[seq]; ok {
+			deltaMicros := int64(arrival.Sub(prevArrival) / time.Microsecond)
+			symbol = rtcp.TypeTCCPacketReceivedSmallDelta
+			if deltaMicros < 0 || deltaMicros/int64(twccDeltaUnit/time.Microsecond) > 255 {
+				symbol = rtcp.TypeTCCPacketReceivedLargeDelta
+			}
+			deltas = append(deltas, &rtcp.RecvDelta{Type: symbol, Delta: deltaMicros})
+			prevArrival = arrival
+		}
+
+		if runLength > 0 && symbol != runSymbol {
+			flushRun()
+			runLength = 0
+		}
+		runSymbol = symbol
+		runLength++
+
+		if seq == last {
+			break
+		}
+	}
+	flushRun()
+
+	return &rtcp.TransportLayerCC{
+		BaseSequenceNumber: base,
+		PacketStatusCount:  count,
+		ReferenceTime:      uint32(referenceTicks),
+		FbPktCount:         fbPktCount,
+		PacketChunks:       chunks,
+		RecvDeltas:         deltas,
+	}
+}
+
+// twccPacketStatuses expands fb.PacketChunks into one received/not-received
+// bool per sequence number covered by the feedback packet, in order
+// starting at fb.BaseSequenceNumber, so processBandwidthFeedback can walk
+// it in lockstep with fb.RecvDeltas (which only has one entry per received
+// packet, not one per sequence number).
+func twccPacketStatuses(fb *rtcp.TransportLayerCC) []bool {
+	statuses := make([]bool, 0, fb.PacketStatusCount)
+
+	for _, chunk := range fb.PacketChunks {
+		switch c := chunk.(type) {
+		case *rtcp.RunLengthChunk:
+			received := c.PacketStatusSymbol != rtcp.TypeTCCPacketNotReceived
+			for i := uint16(0); i < c.RunLength; i++ {
+				statuses = append(statuses, received)
+			}
+
+		case *rtcp.StatusVectorChunk:
+			for _, symbol := range c.SymbolList {
+				statuses = append(statuses, symbol != rtcp.TypeTCCPacketNotReceived)
+			}
+		}
+	}
+
+	if uint16(len(statuses)) > fb.PacketStatusCount {
+		statuses = statuses[:fb.PacketStatusCount]
+	}
+
+	return statuses
+}
@@ -0,0 +1,80 @@
+package gortsplib
+
+import (
+	"testing"
+	"time"
+)
+
+func TestTWCCSenderDeparture(t *testing.T) {
+	s := &twccSender{}
+
+	now := time.Now()
+	s.departures[5%twccCacheSize] = twccDeparture{seq: 5, time: now, valid: true}
+
+	departure, ok := s.departureFor(5)
+	if !ok || !departure.Equal(now) {
+		t.Fatalf("got (%v, %v), want (%v, true)", departure, ok, now)
+	}
+
+	if _, ok := s.departureFor(6); ok {
+		t.Fatal("expected ok = false for a sequence number that was never recorded")
+	}
+}
+
+func TestTWCCSenderDepartureZeroSlotNeverWritten(t *testing.T) {
+	s := &twccSender{}
+
+	// an untouched ring-buffer slot is the zero twccDeparture{}, which has
+	// seq == 0 just like a legitimately recorded seq 0 departure would;
+	// valid is what tells them apart.
+	if _, ok := s.departureFor(0); ok {
+		t.Fatal("expected ok = false for seq 0 before writeInterceptor ever recorded it")
+	}
+}
+
+func TestTWCCSenderDepartureStaleSlotIsRejected(t *testing.T) {
+	s := &twccSender{}
+
+	// seq 5 and seq 5+twccCacheSize alias to the same ring-buffer slot; once
+	// the newer one overwrites it, the older one must no longer resolve.
+	s.departures[5%twccCacheSize] = twccDeparture{seq: 5 + twccCacheSize, time: time.Now(), valid: true}
+
+	if _, ok := s.departureFor(5); ok {
+		t.Fatal("expected ok = false once the slot was overwritten by a newer sequence number")
+	}
+}
+
+func TestTWCCFeedbackRoundTrip(t *testing.T) {
+	base := time.Unix(1_700_000_000, 0)
+	arrivals := map[uint16]time.Time{
+		100: base,
+		101: base.Add(5 * time.Millisecond),
+		103: base.Add(11 * time.Millisecond), // 102 missing: not received
+	}
+
+	fb := buildTWCCFeedback(arrivals, 7)
+
+	if fb.BaseSequenceNumber != 100 {
+		t.Fatalf("BaseSequenceNumber = %d, want 100", fb.BaseSequenceNumber)
+	}
+	if fb.PacketStatusCount != 4 {
+		t.Fatalf("PacketStatusCount = %d, want 4", fb.PacketStatusCount)
+	}
+	if fb.FbPktCount != 7 {
+		t.Fatalf("FbPktCount = %d, want 7", fb.FbPktCount)
+	}
+	if len(fb.RecvDeltas) != 3 {
+		t.Fatalf("len(RecvDeltas) = %d, want 3", len(fb.RecvDeltas))
+	}
+
+	statuses := twccPacketStatuses(fb)
+	want := []bool{true, true, false, true}
+	if len(statuses) != len(want) {
+		t.Fatalf("len(statuses) = %d, want %d", len(statuses), len(want))
+	}
+	for i := range want {
+		if statuses[i] != want[i] {
+			t.Fatalf("statuses[%d] = %v, want %v", i, statuses[i], want[i])
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package gortsplib
+
+import (
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// RTPInterceptorFunc is the prototype of a function that inspects or
+// rewrites an RTP packet flowing through a ServerSession, in either
+// direction. Returning nil drops the packet instead of forwarding it.
+type RTPInterceptorFunc func(pkt *rtp.Packet) *rtp.Packet
+
+// RTCPInterceptorFunc is the prototype of a function that inspects or
+// rewrites an RTCP packet flowing through a ServerSession, in either
+// direction. Returning nil drops the packet instead of forwarding it.
+type RTCPInterceptorFunc func(pkt rtcp.Packet) rtcp.Packet
+
+// interceptorChain is an ordered list of RTP/RTCP interceptors, applied in
+// registration order in both directions, on the way out to the wire and on
+// the way in from it. An interceptor returning nil stops the chain.
+type interceptorChain struct {
+	rtpRead   []RTPInterceptorFunc
+	rtpWrite  []RTPInterceptorFunc
+	rtcpRead  []RTCPInterceptorFunc
+	rtcpWrite []RTCPInterceptorFunc
+}
+
+func (c *interceptorChain) addRTPRead(f RTPInterceptorFunc)    { c.rtpRead = append(c.rtpRead, f) }
+func (c *interceptorChain) addRTPWrite(f RTPInterceptorFunc)   { c.rtpWrite = append(c.rtpWrite, f) }
+func (c *interceptorChain) addRTCPRead(f RTCPInterceptorFunc)  { c.rtcpRead = append(c.rtcpRead, f) }
+func (c *interceptorChain) addRTCPWrite(f RTCPInterceptorFunc) { c.rtcpWrite = append(c.rtcpWrite, f) }
+
+func (c *interceptorChain) applyRTPRead(pkt *rtp.Packet) *rtp.Packet {
+	for _, f := range c.rtpRead {
+		if pkt == nil {
+			return nil
+		}
+		pkt = f(pkt)
+	}
+	return pkt
+}
+
+func (c *interceptorChain) applyRTPWrite(pkt *rtp.Packet) *rtp.Packet {
+	for _, f := range c.rtpWrite {
+		if pkt == nil {
+			return nil
+		}
+		pkt = f(pkt)
+	}
+	return pkt
+}
+
+func (c *interceptorChain) applyRTCPRead(pkt rtcp.Packet) rtcp.Packet {
+	for _, f := range c.rtcpRead {
+		if pkt == nil {
+			return nil
+		}
+		pkt = f(pkt)
+	}
+	return pkt
+}
+
+func (c *interceptorChain) applyRTCPWrite(pkt rtcp.Packet) rtcp.Packet {
+	for _, f := range c.rtcpWrite {
+		if pkt == nil {
+			return nil
+		}
+		pkt = f(pkt)
+	}
+	return pkt
+}
+
+// AddRTPReadInterceptor registers an interceptor that runs, in registration
+// order, on every RTP packet received for this media before it is
+// dispatched to the configured format.
+func (sm *serverSessionMedia) AddRTPReadInterceptor(f RTPInterceptorFunc) {
+	sm.interceptors.addRTPRead(f)
+}
+
+// AddRTPWriteInterceptor registers an interceptor that runs, in
+// registration order, on every RTP packet sent out for this media.
+func (sm *serverSessionMedia) AddRTPWriteInterceptor(f RTPInterceptorFunc) {
+	sm.interceptors.addRTPWrite(f)
+}
+
+// AddRTCPReadInterceptor registers an interceptor that runs, in
+// registration order, on every RTCP packet received for this media.
+func (sm *serverSessionMedia) AddRTCPReadInterceptor(f RTCPInterceptorFunc) {
+	sm.interceptors.addRTCPRead(f)
+}
+
+// AddRTCPWriteInterceptor registers an interceptor that runs, in
+// registration order, on every RTCP packet sent out for this media.
+func (sm *serverSessionMedia) AddRTCPWriteInterceptor(f RTCPInterceptorFunc) {
+	sm.interceptors.addRTCPWrite(f)
+}
@@ -0,0 +1,199 @@
+package gortsplib
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+const nackCacheSize = 512
+
+// nackResponder caches recently-sent RTP packets and retransmits them on
+// request when a rtcp.TransportLayerNack arrives, and separately generates
+// its own NACKs when a gap is detected on the receive side. Caches and gap
+// tracking are kept per-SSRC, since a serverSessionMedia can carry more
+// than one SSRC at once (simulcast, or a primary plus its RTX stream - see
+// setFormatSSRCs/setFormatRTXSSRCs), and a sequence number on its own only
+// means something within a single SSRC's stream.
+type nackResponder struct {
+	sm *serverSessionMedia
+
+	mutex   sync.Mutex
+	caches  map[uint32]*[nackCacheSize]*rtp.Packet
+	lastSeq map[uint32]uint16
+}
+
+func newNACKResponder(sm *serverSessionMedia) *nackResponder {
+	return &nackResponder{
+		sm:      sm,
+		caches:  make(map[uint32]*[nackCacheSize]*rtp.Packet),
+		lastSeq: make(map[uint32]uint16),
+	}
+}
+
+// writeInterceptor stores a copy of every outgoing packet so it can be
+// retransmitted later, and must be registered with AddRTPWriteInterceptor.
+func (n *nackResponder) writeInterceptor(pkt *rtp.Packet) *rtp.Packet {
+	n.mutex.Lock()
+	cache, ok := n.caches[pkt.SSRC]
+	if !ok {
+		cache = &[nackCacheSize]*rtp.Packet{}
+		n.caches[pkt.SSRC] = cache
+	}
+	cp := *pkt
+	cp.Payload = append([]byte(nil), pkt.Payload...)
+	cache[pkt.SequenceNumber%nackCacheSize] = &cp
+	n.mutex.Unlock()
+	return pkt
+}
+
+// readRTCPInterceptor answers rtcp.TransportLayerNack packets by
+// retransmitting any still-cached packet, and must be registered with
+// AddRTCPReadInterceptor.
+func (n *nackResponder) readRTCPInterceptor(pkt rtcp.Packet) rtcp.Packet {
+	nack, ok := pkt.(*rtcp.TransportLayerNack)
+	if !ok {
+		return pkt
+	}
+
+	for _, pair := range nack.Nacks {
+		for _, seq := range pair.PacketList() {
+			n.mutex.Lock()
+			cache := n.caches[nack.MediaSSRC]
+			var cached *rtp.Packet
+			if cache != nil {
+				cached = cache[seq%nackCacheSize]
+			}
+			n.mutex.Unlock()
+
+			if cached != nil && cached.SequenceNumber == seq && cached.SSRC == nack.MediaSSRC {
+				n.sm.writePacketRTP(cached) //nolint:errcheck
+			}
+		}
+	}
+
+	return pkt
+}
+
+// readRTPInterceptor tracks the incoming sequence number and generates a
+// rtcp.TransportLayerNack for any gap, and must be registered with
+// AddRTPReadInterceptor.
+func (n *nackResponder) readRTPInterceptor(pkt *rtp.Packet) *rtp.Packet {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	lastSeq, hasSeq := n.lastSeq[pkt.SSRC]
+
+	if hasSeq && pkt.SequenceNumber != lastSeq+1 {
+		missing := make([]uint16, 0)
+		for seq := lastSeq + 1; seq != pkt.SequenceNumber; seq++ {
+			missing = append(missing, seq)
+		}
+
+		if len(missing) > 0 && len(missing) < nackCacheSize {
+			nack := &rtcp.TransportLayerNack{
+				MediaSSRC: pkt.SSRC,
+				Nacks:     rtcp.NackPairsFromSequenceNumbers(missing),
+			}
+			n.sm.writePacketRTCP(nack) //nolint:errcheck
+		}
+	}
+
+	n.lastSeq[pkt.SSRC] = pkt.SequenceNumber
+
+	return pkt
+}
+
+// EnableNACK registers a nackResponder on sm: it caches outgoing RTP
+// packets for retransmission on request, and generates its own
+// rtcp.TransportLayerNack when it detects a gap in what it receives.
+func (sm *serverSessionMedia) EnableNACK() {
+	n := newNACKResponder(sm)
+	sm.AddRTPWriteInterceptor(n.writeInterceptor)
+	sm.AddRTCPReadInterceptor(n.readRTCPInterceptor)
+	sm.AddRTPReadInterceptor(n.readRTPInterceptor)
+}
+
+// pictureLossForwarder forwards rtcp.PictureLossIndication and
+// rtcp.FullIntraRequest packets to an application-provided callback, so a
+// publisher can react by producing a new key frame.
+type pictureLossForwarder struct {
+	onKeyFrameRequest func()
+}
+
+func newPictureLossForwarder(onKeyFrameRequest func()) *pictureLossForwarder {
+	if onKeyFrameRequest == nil {
+		onKeyFrameRequest = func() {}
+	}
+	return &pictureLossForwarder{onKeyFrameRequest: onKeyFrameRequest}
+}
+
+func (f *pictureLossForwarder) readRTCPInterceptor(pkt rtcp.Packet) rtcp.Packet {
+	switch pkt.(type) {
+	case *rtcp.PictureLossIndication, *rtcp.FullIntraRequest:
+		f.onKeyFrameRequest()
+	}
+	return pkt
+}
+
+// EnablePictureLossForwarding registers a pictureLossForwarder on sm, so
+// onKeyFrameRequest is called whenever a rtcp.PictureLossIndication or
+// rtcp.FullIntraRequest comes in from a reader.
+func (sm *serverSessionMedia) EnablePictureLossForwarding(onKeyFrameRequest func()) {
+	f := newPictureLossForwarder(onKeyFrameRequest)
+	sm.AddRTCPReadInterceptor(f.readRTCPInterceptor)
+}
+
+// senderPacer smooths outgoing RTP writes to a target bitrate instead of
+// releasing them as fast as the application produces them, reducing burst
+// losses on constrained links.
+type senderPacer struct {
+	sm          *serverSessionMedia
+	targetBps   uint64
+	mutex       sync.Mutex
+	lastSent    time.Time
+	bytesInStep uint64
+}
+
+func newSenderPacer(sm *serverSessionMedia, targetBps uint64) *senderPacer {
+	return &senderPacer{sm: sm, targetBps: targetBps, lastSent: time.Time{}}
+}
+
+// writeInterceptor must be registered with AddRTPWriteInterceptor. It
+// blocks the writer goroutine just long enough to keep the session's
+// outgoing byte rate under targetBps.
+func (p *senderPacer) writeInterceptor(pkt *rtp.Packet) *rtp.Packet {
+	if p.targetBps == 0 {
+		return pkt
+	}
+
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	now := p.sm.ss.s.timeNow()
+	if !p.lastSent.IsZero() {
+		elapsed := now.Sub(p.lastSent)
+		allowed := uint64(elapsed.Seconds() * float64(p.targetBps) / 8)
+		if p.bytesInStep > allowed {
+			wait := time.Duration(float64(p.bytesInStep-allowed) * 8 / float64(p.targetBps) * float64(time.Second))
+			time.Sleep(wait)
+			now = now.Add(wait)
+		}
+		p.bytesInStep = 0
+	}
+
+	p.bytesInStep += uint64(len(pkt.Payload))
+	p.lastSent = now
+
+	return pkt
+}
+
+// EnableSenderPacing registers a senderPacer on sm, smoothing its outgoing
+// RTP writes to targetBps instead of releasing them as fast as the
+// application produces them.
+func (sm *serverSessionMedia) EnableSenderPacing(targetBps uint64) {
+	p := newSenderPacer(sm, targetBps)
+	sm.AddRTPWriteInterceptor(p.writeInterceptor)
+}
@@ -0,0 +1,62 @@
+package gortsplib
+
+import (
+	"testing"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+// TestNACKResponderCacheIsPerSSRC makes sure a NACK for one SSRC can't
+// retransmit a same-sequence-number packet cached for a different SSRC on
+// the same serverSessionMedia (simulcast, or a primary stream plus its RTX
+// SSRC). n.sm is left nil: the old, SSRC-blind cache lookup would have
+// matched SSRC 1's cached packet and called n.sm.writePacketRTP on it,
+// panicking on the nil sm; the fixed, SSRC-keyed lookup finds nothing
+// cached for SSRC 2 and returns without touching n.sm at all.
+func TestNACKResponderCacheIsPerSSRC(t *testing.T) {
+	n := newNACKResponder(nil)
+
+	n.writeInterceptor(&rtp.Packet{
+		Header:  rtp.Header{SequenceNumber: 42, SSRC: 1},
+		Payload: []byte{0xaa},
+	})
+
+	nack := &rtcp.TransportLayerNack{
+		MediaSSRC: 2,
+		Nacks:     rtcp.NackPairsFromSequenceNumbers([]uint16{42}),
+	}
+	n.readRTCPInterceptor(nack)
+}
+
+// TestNACKResponderGapTrackingIsPerSSRC makes sure the gap detector used to
+// generate outgoing NACKs doesn't false-positive across multiple incoming
+// SSRCs sharing a serverSessionMedia: a second SSRC starting at a sequence
+// number far from the first SSRC's last one must not look like a gap.
+func TestNACKResponderGapTrackingIsPerSSRC(t *testing.T) {
+	n := newNACKResponder(nil)
+
+	n.readRTPInterceptor(&rtp.Packet{Header: rtp.Header{SequenceNumber: 10, SSRC: 1}})
+
+	// before the fix, this would see pkt.SequenceNumber (100) != lastSeq+1
+	// (11) and call n.sm.writePacketRTCP, panicking on the nil sm.
+	n.readRTPInterceptor(&rtp.Packet{Header: rtp.Header{SequenceNumber: 100, SSRC: 2}})
+}
+
+func TestPictureLossForwarderCallsBack(t *testing.T) {
+	called := 0
+	f := newPictureLossForwarder(func() { called++ })
+
+	f.readRTCPInterceptor(&rtcp.PictureLossIndication{})
+	f.readRTCPInterceptor(&rtcp.FullIntraRequest{})
+	f.readRTCPInterceptor(&rtcp.ReceiverReport{})
+
+	if called != 2 {
+		t.Fatalf("called = %d, want 2", called)
+	}
+}
+
+func TestPictureLossForwarderNilCallback(t *testing.T) {
+	f := newPictureLossForwarder(nil)
+	f.readRTCPInterceptor(&rtcp.PictureLossIndication{})
+}
@@ -0,0 +1,49 @@
+package gortsplib
+
+import (
+	"testing"
+
+	"github.com/pion/rtcp"
+	"github.com/pion/rtp"
+)
+
+func TestInterceptorChainAppliesInRegistrationOrder(t *testing.T) {
+	var c interceptorChain
+	var order []int
+
+	c.addRTPWrite(func(pkt *rtp.Packet) *rtp.Packet {
+		order = append(order, 1)
+		return pkt
+	})
+	c.addRTPWrite(func(pkt *rtp.Packet) *rtp.Packet {
+		order = append(order, 2)
+		return pkt
+	})
+
+	out := c.applyRTPWrite(&rtp.Packet{Header: rtp.Header{SequenceNumber: 1}})
+	if out == nil {
+		t.Fatal("applyRTPWrite dropped the packet unexpectedly")
+	}
+	if len(order) != 2 || order[0] != 1 || order[1] != 2 {
+		t.Fatalf("interceptors ran out of order: %v", order)
+	}
+}
+
+func TestInterceptorChainDropsOnNil(t *testing.T) {
+	var c interceptorChain
+	c.addRTCPWrite(func(rtcp.Packet) rtcp.Packet { return nil })
+
+	called := false
+	c.addRTCPWrite(func(pkt rtcp.Packet) rtcp.Packet {
+		called = true
+		return pkt
+	})
+
+	out := c.applyRTCPWrite(&rtcp.ReceiverReport{})
+	if out != nil {
+		t.Fatalf("expected nil after a dropping interceptor, got %v", out)
+	}
+	if called {
+		t.Fatal("interceptor chain kept running after a packet was dropped")
+	}
+}
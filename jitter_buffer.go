@@ -0,0 +1,160 @@
+package gortsplib
+
+import (
+	"sync"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+// OnPacketLostFunc is the prototype of the function passed to
+// serverSessionMedia.OnPacketLost (and on to newJitterBuffer). pt is the
+// payload type of the format the buffer is attached to.
+type OnPacketLostFunc func(pt uint8, seq uint16)
+
+// defaultJitterBufferDelay is used when Server.JitterBufferDelay is zero,
+// just as newJitterBuffer falls back to a size of 512 when
+// Server.JitterBufferSize is zero: without it, a Server that doesn't set
+// JitterBufferDelay explicitly would hand newJitterBuffer a zero delay,
+// and time.NewTicker panics on a non-positive interval.
+const defaultJitterBufferDelay = 200 * time.Millisecond
+
+// jitterBuffer reorders incoming RTP packets for a single format (keyed by
+// payload type, mirroring serverSessionMedia.formats) and delivers them to
+// the application in sequence-number order, waiting up to delay for
+// out-of-order arrivals before giving up on a gap.
+//
+// It mirrors the jitter packages used by SFUs such as galene: a bounded
+// ring indexed by sequence number, drained by a timer goroutine instead of
+// delivering packets as soon as they arrive.
+type jitterBuffer struct {
+	pt           uint8
+	size         int
+	delay        time.Duration
+	deliver      func(*rtp.Packet)
+	onPacketLost OnPacketLostFunc
+
+	mutex      sync.Mutex
+	ring       map[uint16]jitterBufferEntry
+	nextSeq    uint16
+	hasNextSeq bool
+	closed     chan struct{}
+}
+
+type jitterBufferEntry struct {
+	pkt      *rtp.Packet
+	received time.Time
+}
+
+func newJitterBuffer(
+	pt uint8,
+	size int,
+	delay time.Duration,
+	deliver func(*rtp.Packet),
+	onPacketLost OnPacketLostFunc,
+) *jitterBuffer {
+	if size <= 0 {
+		size = 512
+	}
+	if delay <= 0 {
+		delay = defaultJitterBufferDelay
+	}
+	if onPacketLost == nil {
+		onPacketLost = func(uint8, uint16) {}
+	}
+
+	jb := &jitterBuffer{
+		pt:           pt,
+		size:         size,
+		delay:        delay,
+		deliver:      deliver,
+		onPacketLost: onPacketLost,
+		ring:         make(map[uint16]jitterBufferEntry),
+		closed:       make(chan struct{}),
+	}
+
+	go jb.run()
+
+	return jb
+}
+
+// push inserts an incoming packet into the ring, dropping it silently if
+// the ring is already full (the drain loop is falling behind) or if its
+// sequence number is behind the next one to deliver (it was already
+// delivered or reported lost).
+func (jb *jitterBuffer) push(pkt *rtp.Packet, now time.Time) {
+	jb.mutex.Lock()
+	defer jb.mutex.Unlock()
+
+	if !jb.hasNextSeq {
+		jb.nextSeq = pkt.SequenceNumber
+		jb.hasNextSeq = true
+	}
+
+	if int16(pkt.SequenceNumber-jb.nextSeq) < 0 {
+		return
+	}
+
+	if len(jb.ring) >= jb.size {
+		return
+	}
+
+	jb.ring[pkt.SequenceNumber] = jitterBufferEntry{pkt: pkt, received: now}
+}
+
+// run periodically drains in-order packets, and after delay has elapsed
+// without a missing sequence number showing up, reports it lost and skips
+// over it so later packets aren't held up indefinitely.
+func (jb *jitterBuffer) run() {
+	ticker := time.NewTicker(jb.delay / 4)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-jb.closed:
+			return
+		case now := <-ticker.C:
+			jb.drain(now)
+		}
+	}
+}
+
+func (jb *jitterBuffer) drain(now time.Time) {
+	jb.mutex.Lock()
+	defer jb.mutex.Unlock()
+
+	for jb.hasNextSeq {
+		entry, ok := jb.ring[jb.nextSeq]
+		if ok {
+			delete(jb.ring, jb.nextSeq)
+			jb.nextSeq++
+			jb.deliver(entry.pkt)
+			continue
+		}
+
+		// is there a later packet already old enough that nextSeq must be lost?
+		oldest, found := jb.oldestEntryLocked()
+		if !found || now.Sub(oldest.received) < jb.delay {
+			return
+		}
+
+		jb.onPacketLost(jb.pt, jb.nextSeq)
+		jb.nextSeq++
+	}
+}
+
+func (jb *jitterBuffer) oldestEntryLocked() (jitterBufferEntry, bool) {
+	var oldest jitterBufferEntry
+	found := false
+	for _, entry := range jb.ring {
+		if !found || entry.received.Before(oldest.received) {
+			oldest = entry
+			found = true
+		}
+	}
+	return oldest, found
+}
+
+func (jb *jitterBuffer) close() {
+	close(jb.closed)
+}
@@ -0,0 +1,50 @@
+package gortsplib
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pion/rtp"
+)
+
+func TestJitterBufferDefaultDelay(t *testing.T) {
+	jb := newJitterBuffer(0, 0, 0, func(*rtp.Packet) {}, nil)
+	defer jb.close()
+
+	if jb.delay != defaultJitterBufferDelay {
+		t.Fatalf("delay = %v, want %v", jb.delay, defaultJitterBufferDelay)
+	}
+	if jb.size != 512 {
+		t.Fatalf("size = %v, want 512", jb.size)
+	}
+}
+
+func TestJitterBufferReordersAndDeliversInOrder(t *testing.T) {
+	var delivered []uint16
+	done := make(chan struct{})
+
+	jb := newJitterBuffer(0, 16, 20*time.Millisecond, func(pkt *rtp.Packet) {
+		delivered = append(delivered, pkt.SequenceNumber)
+		if len(delivered) == 3 {
+			close(done)
+		}
+	}, nil)
+	defer jb.close()
+
+	now := time.Now()
+	jb.push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 1}}, now)
+	jb.push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 3}}, now)
+	jb.push(&rtp.Packet{Header: rtp.Header{SequenceNumber: 2}}, now)
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatalf("packets were not delivered in order, got %v", delivered)
+	}
+
+	for i, seq := range delivered {
+		if seq != uint16(i+1) {
+			t.Fatalf("delivered[%d] = %d, want %d", i, seq, i+1)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+// Package hls implements an HLS / Low-Latency HLS egress server that can
+// be fed RTP packets from a gortsplib ServerStream, so an RTSP server can
+// double as an HLS origin.
+package hls
+
+import (
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// DefaultSegmentDuration is used when Server.SegmentDuration is zero.
+const DefaultSegmentDuration = 1 * time.Second
+
+// DefaultPartDuration is used when Server.PartDuration is zero.
+const DefaultPartDuration = 200 * time.Millisecond
+
+// Server serves one or more Tracks as HLS / LL-HLS over HTTP. A Server
+// corresponds to a single RTSP stream: every Track shares the same
+// playlist request path, distinguished by the `?track=` query parameter.
+type Server struct {
+	Address         string
+	SegmentDuration time.Duration
+	PartDuration    time.Duration
+
+	mutex      sync.RWMutex
+	tracks     map[string]*Track
+	httpServer *http.Server
+}
+
+// NewServer allocates a Server. Call AddTrack for every media/format the
+// source stream carries, then Start.
+func NewServer(address string, segmentDuration, partDuration time.Duration) *Server {
+	if segmentDuration == 0 {
+		segmentDuration = DefaultSegmentDuration
+	}
+	if partDuration == 0 {
+		partDuration = DefaultPartDuration
+	}
+
+	return &Server{
+		Address:         address,
+		SegmentDuration: segmentDuration,
+		PartDuration:    partDuration,
+		tracks:          make(map[string]*Track),
+	}
+}
+
+// AddTrack registers a new elementary stream (one per RTSP media) under
+// the given id, which must be unique within the server and is used as the
+// `?track=` query parameter of every HTTP request and playlist URI for the
+// track. No multivariant playlist is served.
+func (s *Server) AddTrack(id string, codec TrackCodec) (*Track, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	t := newTrack(id, codec, s.SegmentDuration, s.PartDuration)
+	s.tracks[id] = t
+	return t, nil
+}
+
+// Start begins serving HTTP requests on Address. It returns once the
+// listener is up; serving happens in a background goroutine, mirroring
+// the non-blocking Start() used by gortsplib.Server itself.
+func (s *Server) Start() error {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", s.onRequest)
+
+	s.httpServer = &http.Server{
+		Addr:    s.Address,
+		Handler: mux,
+	}
+
+	ln, err := net.Listen("tcp", s.Address)
+	if err != nil {
+		return err
+	}
+
+	go s.httpServer.Serve(ln) //nolint:errcheck
+
+	return nil
+}
+
+// Close shuts down the HTTP server and every track's segmenter.
+func (s *Server) Close() error {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	for _, t := range s.tracks {
+		t.close()
+	}
+
+	if s.httpServer != nil {
+		return s.httpServer.Close()
+	}
+
+	return nil
+}
+
+func (s *Server) track(id string) *Track {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	return s.tracks[id]
+}
@@ -0,0 +1,95 @@
+package hls
+
+import (
+	"net/http"
+	"strconv"
+)
+
+// onRequest dispatches every HTTP request to this server: media playlists
+// (optionally blocking for LL-HLS), full segments and individual parts.
+// All three take the track id via `?track=`.
+func (s *Server) onRequest(w http.ResponseWriter, r *http.Request) {
+	trackID := r.URL.Query().Get("track")
+	t := s.track(trackID)
+	if t == nil {
+		http.NotFound(w, r)
+		return
+	}
+
+	switch {
+	case r.URL.Path == "/" || r.URL.Path == "/playlist.m3u8":
+		s.servePlaylist(w, r, t)
+	case r.URL.Path == "/segment":
+		s.serveSegment(w, r, t)
+	case r.URL.Path == "/part":
+		s.servePart(w, r, t)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) servePlaylist(w http.ResponseWriter, r *http.Request, t *Track) {
+	// LL-HLS blocking playlist reload: hold the request open until the
+	// requested segment/part exists.
+	if msnStr := r.URL.Query().Get("_HLS_msn"); msnStr != "" {
+		msn, err := strconv.ParseUint(msnStr, 10, 64)
+		if err == nil {
+			part, _ := strconv.ParseUint(r.URL.Query().Get("_HLS_part"), 10, 64)
+			t.waitForSegment(msn, part)
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+	w.Write([]byte(mediaPlaylist(t))) //nolint:errcheck
+}
+
+func (s *Server) serveSegment(w http.ResponseWriter, r *http.Request, t *Track) {
+	id, err := strconv.ParseUint(r.URL.Query().Get("seg"), 10, 64)
+	if err != nil {
+		http.Error(w, "invalid seg", http.StatusBadRequest)
+		return
+	}
+
+	segments, cur := t.snapshot()
+	for _, seg := range segments {
+		if seg.id == id {
+			w.Header().Set("Content-Type", "video/mp2t")
+			w.Write(seg.bytesFull()) //nolint:errcheck
+			return
+		}
+	}
+	if cur != nil && cur.id == id {
+		w.Header().Set("Content-Type", "video/mp2t")
+		w.Write(cur.bytesFull()) //nolint:errcheck
+		return
+	}
+
+	http.NotFound(w, r)
+}
+
+func (s *Server) servePart(w http.ResponseWriter, r *http.Request, t *Track) {
+	segID, err1 := strconv.ParseUint(r.URL.Query().Get("seg"), 10, 64)
+	partIdx, err2 := strconv.Atoi(r.URL.Query().Get("part"))
+	if err1 != nil || err2 != nil {
+		http.Error(w, "invalid seg/part", http.StatusBadRequest)
+		return
+	}
+
+	t.waitForSegment(segID, uint64(partIdx))
+
+	segments, cur := t.snapshot()
+	for _, seg := range segments {
+		if seg.id == segID {
+			w.Header().Set("Content-Type", "video/mp2t")
+			w.Write(seg.partBytes(partIdx)) //nolint:errcheck
+			return
+		}
+	}
+	if cur != nil && cur.id == segID {
+		w.Header().Set("Content-Type", "video/mp2t")
+		w.Write(cur.partBytes(partIdx)) //nolint:errcheck
+		return
+	}
+
+	http.NotFound(w, r)
+}
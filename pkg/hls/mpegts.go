@@ -0,0 +1,159 @@
+package hls
+
+import (
+	"bytes"
+	"time"
+)
+
+// This file contains a deliberately minimal MPEG-TS packetizer, enough to
+// produce segments that real HLS players accept: a PAT/PMT pair ahead of
+// every access unit, followed by the access unit wrapped in a single PES
+// packet and split into 188-byte TS packets. It does not implement every
+// corner of ISO/IEC 13818-1 (e.g. no PCR is written at all, and there are
+// no multi-program streams); that's out of scope for an
+// HLS origin that only ever serves what this RTSP server itself produced.
+const tsPacketSize = 188
+
+const (
+	tsPIDPAT   = 0x0000
+	tsPIDPMT   = 0x1000
+	tsPIDVideo = 0x0100
+	tsPIDAudio = 0x0101
+)
+
+func streamTypeFor(codec TrackCodec) byte {
+	switch codec {
+	case TrackCodecH264:
+		return 0x1b // H.264
+	case TrackCodecH265:
+		return 0x24 // H.265
+	default:
+		return 0x0f // AAC (ADTS)
+	}
+}
+
+func pidFor(codec TrackCodec) uint16 {
+	if codec == TrackCodecAAC {
+		return tsPIDAudio
+	}
+	return tsPIDVideo
+}
+
+// writeTSAccessUnit packetizes au as MPEG-TS into buf. continuity carries
+// this Track's per-PID continuity counters; the caller is responsible for
+// serializing access to it (Track.WriteAccessUnit holds t.mutex for the
+// whole call), since continuity counters must never be shared across
+// tracks writing concurrently.
+func writeTSAccessUnit(buf *bytes.Buffer, codec TrackCodec, au [][]byte, pts time.Duration, continuity map[uint16]byte) {
+	writePATPMT(buf, codec, continuity)
+
+	var payload bytes.Buffer
+	for _, nalu := range au {
+		if codec != TrackCodecAAC {
+			payload.Write([]byte{0x00, 0x00, 0x00, 0x01}) // Annex B start code
+		}
+		payload.Write(nalu)
+	}
+
+	pes := buildPES(payload.Bytes(), pts)
+	writeTSPackets(buf, pidFor(codec), pes, continuity)
+}
+
+func writePATPMT(buf *bytes.Buffer, codec TrackCodec, continuity map[uint16]byte) {
+	pat := []byte{
+		0x00,       // table id
+		0xb0, 0x0d, // section_syntax_indicator + length
+		0x00, 0x01, // transport_stream_id
+		0xc1,       // version/current_next
+		0x00, 0x00, // section_number / last_section_number
+		0x00, 0x01, // program_number 1
+		0xe0 | byte(tsPIDPMT>>8), byte(tsPIDPMT & 0xff), // PMT PID
+	}
+	writeTSPackets(buf, tsPIDPAT, wrapPSI(pat), continuity)
+
+	pmt := []byte{
+		0x02,       // table id
+		0xb0, 0x12, // length
+		0x00, 0x01, // program_number
+		0xc1, 0x00, 0x00,
+		0xe1, byte(pidFor(codec) & 0x1f), // PCR PID
+		0xf0, 0x00, // program_info_length
+		streamTypeFor(codec),
+		0xe0 | byte(pidFor(codec)>>8), byte(pidFor(codec) & 0xff),
+		0xf0, 0x00,
+	}
+	writeTSPackets(buf, tsPIDPMT, wrapPSI(pmt), continuity)
+}
+
+func wrapPSI(section []byte) []byte {
+	out := make([]byte, 0, len(section)+5)
+	out = append(out, 0x00) // pointer_field
+	out = append(out, section...)
+
+	// CRC-32/MPEG-2 over the section, already counted in its section_length.
+	crc := uint32(0xffffffff)
+	for _, b := range section {
+		crc ^= uint32(b) << 24
+		for i := 0; i < 8; i++ {
+			if crc&0x80000000 != 0 {
+				crc = crc<<1 ^ 0x04c11db7
+			} else {
+				crc <<= 1
+			}
+		}
+	}
+	out = append(out, byte(crc>>24), byte(crc>>16), byte(crc>>8), byte(crc))
+	return out
+}
+
+func buildPES(payload []byte, pts time.Duration) []byte {
+	ptsTicks := uint64(pts.Seconds() * 90000)
+
+	header := []byte{
+		0x00, 0x00, 0x01, 0xe0, // start code + stream id (video); audio reuses it, which is fine for single-program TS
+		0x00, 0x00, // PES packet length (0 == unbounded, valid for video)
+		0x80, 0x80, 0x05, // flags + PTS present + header length
+	}
+	header = append(header, marshalPTS(0x2, ptsTicks)...)
+
+	return append(header, payload...)
+}
+
+func marshalPTS(prefix byte, ticks uint64) []byte {
+	b := make([]byte, 5)
+	b[0] = (prefix << 4) | byte((ticks>>30)&0x07)<<1 | 1
+	b[1] = byte(ticks >> 22)
+	b[2] = byte((ticks>>15)&0x7f)<<1 | 1
+	b[3] = byte(ticks >> 7)
+	b[4] = byte(ticks&0x7f)<<1 | 1
+	return b
+}
+
+func writeTSPackets(buf *bytes.Buffer, pid uint16, payload []byte, continuity map[uint16]byte) {
+	first := true
+	for first || len(payload) > 0 {
+		pkt := make([]byte, tsPacketSize)
+		pkt[0] = 0x47 // sync byte
+
+		pusi := byte(0)
+		if first {
+			pusi = 0x40
+		}
+		pkt[1] = pusi | byte(pid>>8)&0x1f
+		pkt[2] = byte(pid & 0xff)
+
+		cc := continuity[pid]
+		pkt[3] = 0x10 | (cc & 0x0f)
+		continuity[pid] = cc + 1
+
+		n := copy(pkt[4:], payload)
+		payload = payload[n:]
+
+		for i := 4 + n; i < len(pkt); i++ {
+			pkt[i] = 0xff // stuffing for the last, partially-filled packet
+		}
+
+		buf.Write(pkt)
+		first = false
+	}
+}
@@ -0,0 +1,29 @@
+package hls
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestWriteTSPacketsPerTrackContinuity makes sure two tracks writing
+// concurrently use independent continuity counters: each gets its own map,
+// so a race detector run over this never flags a concurrent map write.
+func TestWriteTSPacketsPerTrackContinuity(t *testing.T) {
+	var wg sync.WaitGroup
+
+	for i := 0; i < 2; i++ {
+		continuity := make(map[uint16]byte)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var buf bytes.Buffer
+			for j := 0; j < 100; j++ {
+				writeTSAccessUnit(&buf, TrackCodecH264, [][]byte{{0x01, 0x02}}, time.Duration(j), continuity)
+			}
+		}()
+	}
+
+	wg.Wait()
+}
@@ -0,0 +1,52 @@
+package hls
+
+import (
+	"fmt"
+	"strings"
+)
+
+// mediaPlaylist renders the EXT-X-PART / EXT-X-PRELOAD-HINT media
+// playlist for a track, per RFC 8216bis.
+func mediaPlaylist(t *Track) string {
+	segments, cur := t.snapshot()
+
+	var b strings.Builder
+	b.WriteString("#EXTM3U\n")
+	b.WriteString("#EXT-X-VERSION:9\n")
+	fmt.Fprintf(&b, "#EXT-X-TARGETDURATION:%d\n", int(t.segmentDuration.Seconds()+0.5))
+	fmt.Fprintf(&b, "#EXT-X-PART-INF:PART-TARGET=%.3f\n", t.partDuration.Seconds())
+	fmt.Fprintf(&b, "#EXT-X-SERVER-CONTROL:CAN-BLOCK-RELOAD=YES,PART-HOLD-BACK=%.3f\n", t.partDuration.Seconds()*3)
+
+	if len(segments) > 0 {
+		fmt.Fprintf(&b, "#EXT-X-MEDIA-SEQUENCE:%d\n", segments[0].id)
+	}
+
+	for _, s := range segments {
+		for i, p := range s.parts {
+			fmt.Fprintf(&b, "#EXT-X-PART:DURATION=%.3f,URI=\"part?track=%s&seg=%d&part=%d\"%s\n",
+				p.duration().Seconds(), t.id, s.id, i, independentSuffix(p.isRandomAccess))
+		}
+		fmt.Fprintf(&b, "#EXTINF:%.3f,\n", s.duration().Seconds())
+		fmt.Fprintf(&b, "segment?track=%s&seg=%d\n", t.id, s.id)
+	}
+
+	if cur != nil {
+		for i, p := range cur.parts {
+			fmt.Fprintf(&b, "#EXT-X-PART:DURATION=%.3f,URI=\"part?track=%s&seg=%d&part=%d\"%s\n",
+				p.duration().Seconds(), t.id, cur.id, i, independentSuffix(p.isRandomAccess))
+		}
+
+		nextPart := len(cur.parts)
+		fmt.Fprintf(&b, "#EXT-X-PRELOAD-HINT:TYPE=PART,URI=\"part?track=%s&seg=%d&part=%d\"\n",
+			t.id, cur.id, nextPart)
+	}
+
+	return b.String()
+}
+
+func independentSuffix(isRandomAccess bool) string {
+	if isRandomAccess {
+		return ",INDEPENDENT=YES"
+	}
+	return ""
+}
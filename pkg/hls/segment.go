@@ -0,0 +1,92 @@
+package hls
+
+import (
+	"bytes"
+	"time"
+)
+
+// part is one LL-HLS partial segment: a short, independently fetchable
+// byte range advertised in the playlist via EXT-X-PART while the segment
+// it belongs to is still being filled in.
+type part struct {
+	startPTS       time.Duration
+	endPTS         time.Duration
+	buf            bytes.Buffer
+	isRandomAccess bool
+	closed         bool
+}
+
+func (p *part) duration() time.Duration {
+	return p.endPTS - p.startPTS
+}
+
+// segment is one HLS media segment, made up of one or more parts. Parts
+// are appended to parts as they close, so the segment's own bytes (see
+// bytesFull) are always the concatenation of all closed parts plus
+// whatever is in the still-open one.
+type segment struct {
+	id       uint64
+	codec    TrackCodec
+	startPTS time.Duration
+	parts    []*part
+	curPart  *part
+	closed   bool
+}
+
+func newSegment(id uint64, codec TrackCodec, startPTS time.Duration) *segment {
+	s := &segment{
+		id:       id,
+		codec:    codec,
+		startPTS: startPTS,
+	}
+	s.curPart = &part{startPTS: startPTS, endPTS: startPTS}
+	return s
+}
+
+func (s *segment) duration() time.Duration {
+	if s.curPart == nil {
+		return 0
+	}
+	return s.curPart.endPTS - s.startPTS
+}
+
+// writeAccessUnit packetizes au as MPEG-TS and appends it to the
+// currently-open part. continuity is the owning Track's per-PID
+// continuity counters; see writeTSAccessUnit for its locking contract.
+func (s *segment) writeAccessUnit(au [][]byte, pts time.Duration, isRandomAccess bool, continuity map[uint16]byte) {
+	writeTSAccessUnit(&s.curPart.buf, s.codec, au, pts, continuity)
+	s.curPart.endPTS = pts
+	if isRandomAccess {
+		s.curPart.isRandomAccess = true
+	}
+}
+
+// closePart freezes the current part (so it stops growing and can be
+// advertised as a finished EXT-X-PART) and opens a new one.
+func (s *segment) closePart() {
+	s.curPart.closed = true
+	s.parts = append(s.parts, s.curPart)
+	s.curPart = &part{startPTS: s.curPart.endPTS, endPTS: s.curPart.endPTS}
+}
+
+// bytesFull returns the full segment payload: every closed part plus whatever
+// has accumulated in the open one.
+func (s *segment) bytesFull() []byte {
+	var buf bytes.Buffer
+	for _, p := range s.parts {
+		buf.Write(p.buf.Bytes())
+	}
+	if s.curPart != nil {
+		buf.Write(s.curPart.buf.Bytes())
+	}
+	return buf.Bytes()
+}
+
+// partBytes returns the payload of a single part, by index within the
+// segment (closed parts only).
+func (s *segment) partBytes(index int) []byte {
+	if index < 0 || index >= len(s.parts) {
+		return nil
+	}
+	return s.parts[index].buf.Bytes()
+}
@@ -0,0 +1,140 @@
+package hls
+
+import (
+	"sync"
+	"time"
+)
+
+// TrackCodec identifies the codec carried by a Track, so the segmenter can
+// pick the right MPEG-TS stream type and PID (H264/H265 in MPEG-TS, AAC in
+// ADTS-wrapped MPEG-TS). The playlist carries no CODECS attribute.
+type TrackCodec int
+
+// Supported track codecs.
+const (
+	TrackCodecH264 TrackCodec = iota
+	TrackCodecH265
+	TrackCodecAAC
+)
+
+// Track is a single elementary stream (one RTSP media) being segmented
+// into HLS/LL-HLS segments and parts.
+type Track struct {
+	id              string
+	codec           TrackCodec
+	segmentDuration time.Duration
+	partDuration    time.Duration
+
+	mutex      sync.Mutex
+	cond       *sync.Cond
+	segments   []*segment
+	curSegment *segment
+	nextSegID  uint64
+	nextPartID uint64
+	closed     bool
+	continuity map[uint16]byte // MPEG-TS per-PID continuity counters, guarded by mutex
+	lastPTS    time.Duration
+	hasLastPTS bool
+}
+
+func newTrack(id string, codec TrackCodec, segmentDuration, partDuration time.Duration) *Track {
+	t := &Track{
+		id:              id,
+		codec:           codec,
+		segmentDuration: segmentDuration,
+		partDuration:    partDuration,
+		continuity:      make(map[uint16]byte),
+	}
+	t.cond = sync.NewCond(&t.mutex)
+	return t
+}
+
+// WriteAccessUnit appends one depayloaded access unit (a full H264/H265
+// frame, or a single AAC frame) at presentation time pts to the track,
+// rolling the current part/segment when their respective durations are
+// exceeded. Depayloading RTP into access units is the caller's
+// responsibility (done with the existing format package decoders).
+//
+// A Track is meant to be shared by every serverSessionMedia relaying the
+// same published stream (see EnableHLS), since there is no single
+// publisher-side subscription point in this package to feed it from
+// instead; since the sessions feeding it race independently, WriteAccessUnit
+// drops a call whose pts does not strictly advance past the one it last
+// admitted, so redundant or reordered calls from more than one reader
+// session for the same access unit don't re-segment it or walk segment/part
+// end timestamps backwards.
+func (t *Track) WriteAccessUnit(au [][]byte, pts time.Duration, isRandomAccess bool) error {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	if t.closed {
+		return nil
+	}
+
+	if t.hasLastPTS && pts <= t.lastPTS {
+		return nil
+	}
+	t.lastPTS = pts
+	t.hasLastPTS = true
+
+	if t.curSegment == nil || (isRandomAccess && t.curSegment.duration() >= t.segmentDuration) {
+		t.rotateSegmentLocked(pts)
+	}
+
+	t.curSegment.writeAccessUnit(au, pts, isRandomAccess, t.continuity)
+
+	if t.curSegment.curPart.duration() >= t.partDuration {
+		t.curSegment.closePart()
+		t.nextPartID++
+	}
+
+	t.cond.Broadcast()
+
+	return nil
+}
+
+func (t *Track) rotateSegmentLocked(pts time.Duration) {
+	if t.curSegment != nil {
+		t.curSegment.closePart()
+		t.segments = append(t.segments, t.curSegment)
+
+		// keep only the last few segments around, like every HLS origin does.
+		const maxSegments = 7
+		if len(t.segments) > maxSegments {
+			t.segments = t.segments[len(t.segments)-maxSegments:]
+		}
+	}
+
+	t.curSegment = newSegment(t.nextSegID, t.codec, pts)
+	t.nextSegID++
+}
+
+// waitForSegment blocks until segment msn, part part exists (LL-HLS
+// blocking playlist reload), or the track is closed.
+func (t *Track) waitForSegment(msn uint64, part uint64) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+
+	for !t.closed {
+		if t.curSegment != nil && t.curSegment.id >= msn && uint64(len(t.curSegment.parts)) > part {
+			return
+		}
+		if len(t.segments) > 0 && t.segments[len(t.segments)-1].id >= msn {
+			return
+		}
+		t.cond.Wait()
+	}
+}
+
+func (t *Track) snapshot() ([]*segment, *segment) {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	return append([]*segment(nil), t.segments...), t.curSegment
+}
+
+func (t *Track) close() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.closed = true
+	t.cond.Broadcast()
+}
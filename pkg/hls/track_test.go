@@ -0,0 +1,71 @@
+package hls
+
+import (
+	"testing"
+	"time"
+)
+
+// TestWriteAccessUnitDedupesRepeatedPTS makes sure that calling
+// WriteAccessUnit twice for the same pts - as happens when more than one
+// PLAY session relaying the same publisher feeds the same shared Track,
+// see EnableHLS - only segments the access unit once.
+func TestWriteAccessUnitDedupesRepeatedPTS(t *testing.T) {
+	tr := newTrack("cam0", TrackCodecH264, time.Second, 200*time.Millisecond)
+	defer tr.close()
+
+	au := [][]byte{{0x01, 0x02, 0x03}}
+
+	err := tr.WriteAccessUnit(au, 10*time.Millisecond, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	first := len(tr.curSegment.bytesFull())
+
+	// same pts again, as a second PLAY session's interceptor would produce
+	// for the very same frame.
+	err = tr.WriteAccessUnit(au, 10*time.Millisecond, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	second := len(tr.curSegment.bytesFull())
+
+	if second != first {
+		t.Fatalf("segment grew from %d to %d bytes on a duplicate pts, want unchanged", first, second)
+	}
+
+	// a genuinely new access unit must still go through.
+	err = tr.WriteAccessUnit(au, 20*time.Millisecond, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if third := len(tr.curSegment.bytesFull()); third <= second {
+		t.Fatalf("segment did not grow for a new pts: %d -> %d", second, third)
+	}
+}
+
+// TestWriteAccessUnitDropsOutOfOrderPTS makes sure a call whose pts falls
+// behind the last one admitted is dropped too, not just an exact repeat -
+// two PLAY sessions racing to feed the same shared Track can interleave so
+// that a later call carries an earlier pts than one already admitted, and
+// letting that through would walk the current part's end timestamp
+// backwards.
+func TestWriteAccessUnitDropsOutOfOrderPTS(t *testing.T) {
+	tr := newTrack("cam0", TrackCodecH264, time.Second, 200*time.Millisecond)
+	defer tr.close()
+
+	au := [][]byte{{0x01, 0x02, 0x03}}
+
+	if err := tr.WriteAccessUnit(au, 200*time.Millisecond, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	afterFirst := tr.curSegment.curPart.endPTS
+
+	// a second session's call arrives late, carrying an earlier pts than
+	// the one already admitted above.
+	if err := tr.WriteAccessUnit(au, 100*time.Millisecond, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got := tr.curSegment.curPart.endPTS; got != afterFirst {
+		t.Fatalf("endPTS moved from %v to %v on an out-of-order pts, want unchanged", afterFirst, got)
+	}
+}
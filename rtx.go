@@ -0,0 +1,86 @@
+package gortsplib
+
+import (
+	"encoding/binary"
+	"errors"
+	"strconv"
+
+	"github.com/pion/rtp"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+)
+
+// errRTXPacketTooShort is reported when an RTX payload is too short to
+// even contain the mandatory 2-byte OSN (RFC 4588).
+var errRTXPacketTooShort = errors.New("RTX packet too short to contain OSN")
+
+// LayerInfo identifies one simulcast layer (or a single, non-simulcast
+// stream) by the RTP stream id a sender advertised for it, so a
+// serverSessionFormat that sees multiple SSRCs on its own payload type
+// can tell the layers apart.
+type LayerInfo struct {
+	// RID is the RTP stream id (SDP a=rid, RFC 8852) the sender
+	// advertised for this layer, if any.
+	RID string
+}
+
+// SetSSRCs registers every SSRC in layers as belonging to sf, so that
+// readRTPUDPRecord/readRTPTCPRecord route packets carrying those SSRCs to
+// sf even though other formats may share the same payload type. This is
+// needed for simulcast senders, which reuse one payload type across
+// multiple SSRCs/layers.
+func (sf *serverSessionFormat) SetSSRCs(layers map[uint32]LayerInfo) {
+	sf.sm.setFormatSSRCs(sf, layers)
+}
+
+// rtxOriginalPT reports the payload type forma's SDP fmtp advertises as
+// its associated (original) payload type via `apt=`, per RFC 4588, and
+// whether forma is an RTX format at all.
+func rtxOriginalPT(forma description.Format) (uint8, bool) {
+	apt, ok := forma.FMTP()["apt"]
+	if !ok {
+		return 0, false
+	}
+
+	n, err := strconv.ParseUint(apt, 10, 8)
+	if err != nil {
+		return 0, false
+	}
+
+	return uint8(n), true
+}
+
+// decapsulateRTX rewrites pkt in place from its RFC 4588 RTX encoding
+// back into the stream it retransmits: the original sequence number
+// (OSN) is read from the first two bytes of the payload, the payload
+// type is set to origPT, and the OSN prefix is stripped. pkt.SSRC is left
+// untouched, since RFC 4588 has the RTX stream keep its own SSRC rather
+// than carrying the original one in the payload; callers that also need
+// to resolve the original SSRC (simulcast combined with RTX) must do so
+// separately, see SetRTXSSRCs.
+func decapsulateRTX(pkt *rtp.Packet, origPT uint8) error {
+	if len(pkt.Payload) < 2 {
+		return errRTXPacketTooShort
+	}
+
+	pkt.SequenceNumber = binary.BigEndian.Uint16(pkt.Payload[:2])
+	pkt.PayloadType = origPT
+	pkt.Payload = pkt.Payload[2:]
+
+	return nil
+}
+
+// SetRTXSSRCs registers, for each RTX-SSRC -> primary-SSRC pair in
+// mapping, that an RTX packet carrying that RTX SSRC retransmits the
+// stream sent under the given primary SSRC (the pairing SDP advertises
+// via a=ssrc-group:FID, RFC 5576). decapsulateRTX recovers the original
+// sequence number and payload type from an RTX packet, but never its
+// SSRC, since RFC 4588 has the RTX stream keep its own distinct one for
+// the life of the session; without this mapping, resolveRecordFormat's
+// formatForSSRC lookup still sees the RTX SSRC after decapsulation and
+// can't find the simulcast layer a sender registered via SetSSRCs. This
+// is only needed when RTX is combined with simulcast - a lone RTX stream
+// already resolves correctly from its payload type alone.
+func (sf *serverSessionFormat) SetRTXSSRCs(mapping map[uint32]uint32) {
+	sf.sm.setFormatRTXSSRCs(mapping)
+}
@@ -0,0 +1,47 @@
+package gortsplib
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+)
+
+func TestDecapsulateRTX(t *testing.T) {
+	pkt := &rtp.Packet{
+		Header: rtp.Header{
+			SequenceNumber: 999,
+			PayloadType:    97,
+			SSRC:           0x12345678,
+		},
+		Payload: []byte{0x00, 0x2a, 0xaa, 0xbb, 0xcc},
+	}
+
+	err := decapsulateRTX(pkt, 96)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if pkt.SequenceNumber != 42 {
+		t.Fatalf("SequenceNumber = %d, want 42", pkt.SequenceNumber)
+	}
+	if pkt.PayloadType != 96 {
+		t.Fatalf("PayloadType = %d, want 96", pkt.PayloadType)
+	}
+	if string(pkt.Payload) != "\xaa\xbb\xcc" {
+		t.Fatalf("Payload = %v, want aa bb cc", pkt.Payload)
+	}
+	// decapsulateRTX must leave the RTX SSRC untouched; rewriting it to the
+	// primary SSRC is the caller's job (see resolveRecordFormat).
+	if pkt.SSRC != 0x12345678 {
+		t.Fatalf("SSRC = %x, want unchanged", pkt.SSRC)
+	}
+}
+
+func TestDecapsulateRTXTooShort(t *testing.T) {
+	pkt := &rtp.Packet{Payload: []byte{0x00}}
+
+	err := decapsulateRTX(pkt, 96)
+	if err != errRTXPacketTooShort {
+		t.Fatalf("err = %v, want errRTXPacketTooShort", err)
+	}
+}
@@ -0,0 +1,37 @@
+package gortsplib
+
+import (
+	"time"
+
+	"github.com/pion/rtp"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/hls"
+)
+
+// EnableHLS feeds sm's media to the given hls.Track as the egress sink. It
+// registers an RTP write interceptor (see interceptor.go) that passes the
+// payload of every packet this session sends out to depayload, and hands
+// every access unit depayload returns to hls.Track.WriteAccessUnit.
+// depayload is supplied by the caller (built on the existing per-format
+// decoders in pkg/format), and serving the track over HTTP is up to the
+// hls.Server it belongs to, so this function only wires the plumbing
+// between the two subsystems.
+//
+// This wires HLS in per PLAY serverSessionMedia rather than at a single
+// publisher-side subscription point: gortsplib's ServerStream is what
+// broadcasts one RECORD session's packets out to every PLAY reader, but
+// it isn't a type this package defines or can reach into, so there's no
+// "enable HLS once for the whole daemon" entry point here - track must be
+// the same hls.Track passed to every PLAY session's EnableHLS call for one
+// published stream. hls.Track.WriteAccessUnit drops a call that repeats
+// the pts it last admitted, so that sharing still doesn't re-segment the
+// same access unit once per connected reader.
+func (sm *serverSessionMedia) EnableHLS(track *hls.Track, depayload func(pkt []byte) (au [][]byte, pts time.Duration, randomAccess bool)) {
+	sm.AddRTPWriteInterceptor(func(pkt *rtp.Packet) *rtp.Packet {
+		au, pts, randomAccess := depayload(pkt.Payload)
+		if au != nil {
+			track.WriteAccessUnit(au, pts, randomAccess) //nolint:errcheck
+		}
+		return pkt
+	})
+}
@@ -0,0 +1,52 @@
+package gortsplib
+
+import (
+	"time"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+)
+
+// BandwidthEstimate returns the current transport-cc / REMB bandwidth
+// estimate for the given media of a ServerSession.
+func (ss *ServerSession) BandwidthEstimate(medi *description.Media) BandwidthEstimate {
+	sm := ss.medias[medi]
+	if sm == nil {
+		return BandwidthEstimate{}
+	}
+	return sm.BandwidthEstimate()
+}
+
+// OnBandwidthEstimate sets a callback that is called every time the
+// bandwidth estimate for the given media is updated.
+func (ss *ServerSession) OnBandwidthEstimate(medi *description.Media, cb OnBandwidthEstimateFunc) {
+	sm := ss.medias[medi]
+	if sm == nil {
+		return
+	}
+	sm.onBandwidthEstimate = cb
+}
+
+// EnableTransportCC tags every outgoing RTP packet on the given media with
+// a transport-wide sequence number on extensionID (the RTP header
+// extension id negotiated via SDP a=extmap for the transport-cc URI), so
+// that TransportLayerCC feedback received back can drive BandwidthEstimate
+// off real per-packet departure times.
+func (ss *ServerSession) EnableTransportCC(medi *description.Media, extensionID uint8) {
+	sm := ss.medias[medi]
+	if sm == nil {
+		return
+	}
+	sm.EnableTransportCC(extensionID)
+}
+
+// EnableTransportCCFeedback starts periodically emitting TransportLayerCC
+// feedback for RTP packets received on the given media, reading the
+// transport-wide sequence number from extensionID on each one. interval
+// controls how often feedback is sent; a typical value is 50-100ms.
+func (ss *ServerSession) EnableTransportCCFeedback(medi *description.Media, extensionID uint8, interval time.Duration) {
+	sm := ss.medias[medi]
+	if sm == nil {
+		return
+	}
+	sm.EnableTransportCCFeedback(extensionID, interval)
+}
@@ -2,6 +2,7 @@ package gortsplib
 
 import (
 	"net"
+	"sync"
 	"sync/atomic"
 	"time"
 
@@ -25,41 +26,116 @@ type serverSessionMedia struct {
 	tcpRTCPFrame           *base.InterleavedFrame
 	tcpBuffer              []byte
 	formats                map[uint8]*serverSessionFormat // record only
+	rtxFormats             map[uint8]uint8                // record only: RTX payload type -> associated original payload type
 	writePacketRTPInQueue  func([]byte)
 	writePacketRTCPInQueue func([]byte)
 	onPacketRTCP           OnPacketRTCPFunc
+	bwEstimator            *bandwidthEstimator
+	onBandwidthEstimate    OnBandwidthEstimateFunc
+	twccSender             *twccSender            // set by EnableTransportCC
+	twccFeedback           *twccFeedbackGenerator // set by EnableTransportCCFeedback
+	interceptors           interceptorChain
+	srtpContext            *SRTPContext            // set by SetSRTPContext
+	jitterBuffers          map[uint8]*jitterBuffer // record only, keyed like formats
+	onPacketLost           OnPacketLostFunc
+
+	ssrcMutex   sync.RWMutex
+	ssrcFormats map[uint32]*serverSessionFormat // record only, populated by serverSessionFormat.SetSSRCs
+	rtxSSRCs    map[uint32]uint32               // record only: RTX SSRC -> primary SSRC, populated by serverSessionFormat.SetRTXSSRCs
+	formatPTs   map[*serverSessionFormat]uint8  // record only, reverse of formats, built once at construction
 }
 
 func newServerSessionMedia(ss *ServerSession, medi *description.Media) *serverSessionMedia {
 	sm := &serverSessionMedia{
-		ss:           ss,
-		media:        medi,
-		onPacketRTCP: func(rtcp.Packet) {},
+		ss:                  ss,
+		media:               medi,
+		onPacketRTCP:        func(rtcp.Packet) {},
+		bwEstimator:         newBandwidthEstimator(),
+		onBandwidthEstimate: func(BandwidthEstimate) {},
+		onPacketLost:        func(uint8, uint16) {},
+	}
+	sm.bwEstimator.onEstimate = func(be BandwidthEstimate) {
+		sm.onBandwidthEstimate(be)
 	}
 
 	if ss.state == ServerSessionStatePreRecord {
 		sm.formats = make(map[uint8]*serverSessionFormat)
+		sm.jitterBuffers = make(map[uint8]*jitterBuffer)
+		sm.rtxFormats = make(map[uint8]uint8)
+		sm.ssrcFormats = make(map[uint32]*serverSessionFormat)
+		sm.rtxSSRCs = make(map[uint32]uint32)
+		sm.formatPTs = make(map[*serverSessionFormat]uint8)
+		for _, forma := range medi.Formats {
+			pt := forma.PayloadType()
+			sf := newServerSessionFormat(sm, forma)
+			sm.formats[pt] = sf
+			sm.formatPTs[sf] = pt
+		}
 		for _, forma := range medi.Formats {
-			sm.formats[forma.PayloadType()] = newServerSessionFormat(sm, forma)
+			if origPT, ok := rtxOriginalPT(forma); ok {
+				sm.rtxFormats[forma.PayloadType()] = origPT
+			}
 		}
 	}
 
 	return sm
 }
 
+// OnPacketLost sets a callback that is called for every record-path RTP
+// sequence number that the jitter buffer gave up waiting for.
+func (sm *serverSessionMedia) OnPacketLost(cb OnPacketLostFunc) {
+	sm.onPacketLost = cb
+}
+
+// deliverJitterPacket returns the function a jitterBuffer should call to
+// hand an in-order packet to sf, using whichever transport-specific entry
+// point this session was SETUP with.
+func (sm *serverSessionMedia) deliverJitterPacket(sf *serverSessionFormat) func(*rtp.Packet) {
+	return func(pkt *rtp.Packet) {
+		if *sm.ss.setuppedTransport == TransportTCP {
+			sf.readRTPTCP(pkt)
+			return
+		}
+		sf.readRTPUDP(pkt, sm.ss.s.timeNow())
+	}
+}
+
 func (sm *serverSessionMedia) start() {
 	// allocate udpRTCPReceiver before udpRTCPListener
 	// otherwise udpRTCPReceiver.LastSSRC() can't be called.
-	for _, sf := range sm.formats {
+	for pt, sf := range sm.formats {
 		sf.start()
+
+		// RTX packets are decapsulated and rerouted to their original
+		// payload type before ever reaching jitterBuffers, so an RTX
+		// payload type never needs a jitter buffer of its own.
+		if _, isRTX := sm.rtxFormats[pt]; isRTX {
+			continue
+		}
+
+		sm.jitterBuffers[pt] = newJitterBuffer(
+			pt,
+			sm.ss.s.JitterBufferSize,
+			sm.ss.s.JitterBufferDelay,
+			sm.deliverJitterPacket(sf),
+			sm.onPacketLost)
 	}
 
 	switch *sm.ss.setuppedTransport {
-	case TransportUDP, TransportUDPMulticast:
+	case TransportUDP, TransportUDPMulticast, TransportSecure:
 		sm.writePacketRTPInQueue = sm.writePacketRTPInQueueUDP
 		sm.writePacketRTCPInQueue = sm.writePacketRTCPInQueueUDP
 
-		if *sm.ss.setuppedTransport == TransportUDP {
+		// TransportSecure only adds a crypto layer on top of the UDP
+		// framing above; writePacketRTPInQueueUDP/readRTPUDPRecord (and
+		// their RTCP equivalents) already gate encryption/decryption on
+		// sm.srtpContext. If nothing ever called SetSRTPContext, flag it
+		// loudly instead of silently carrying RTP/RTCP in the clear.
+		if *sm.ss.setuppedTransport == TransportSecure && sm.srtpContext == nil {
+			sm.ss.onDecodeError(liberrors.ErrServerSRTPContextNotSet{})
+		}
+
+		if *sm.ss.setuppedTransport == TransportUDP || *sm.ss.setuppedTransport == TransportSecure {
 			if sm.ss.state == ServerSessionStatePlay {
 				// firewall opening is performed with RTCP sender reports generated by ServerStream
 
@@ -106,6 +182,19 @@ func (sm *serverSessionMedia) stop() {
 	for _, sf := range sm.formats {
 		sf.stop()
 	}
+
+	if *sm.ss.setuppedTransport == TransportSecure {
+		sm.ss.s.udpRTPListener.removeClient(sm.ss.author.ip(), sm.udpRTPReadPort)
+		sm.ss.s.udpRTCPListener.removeClient(sm.ss.author.ip(), sm.udpRTCPReadPort)
+	}
+
+	for _, jb := range sm.jitterBuffers {
+		jb.close()
+	}
+
+	if sm.twccFeedback != nil {
+		sm.twccFeedback.close()
+	}
 }
 
 func (sm *serverSessionMedia) findFormatWithSSRC(ssrc uint32) *serverSessionFormat {
@@ -118,17 +207,140 @@ func (sm *serverSessionMedia) findFormatWithSSRC(ssrc uint32) *serverSessionForm
 	return nil
 }
 
+// setFormatSSRCs is called by serverSessionFormat.SetSSRCs to register sf
+// as the destination for every SSRC in layers, so that simulcast senders
+// reusing the same payload type across layers can still be routed to the
+// right serverSessionFormat.
+func (sm *serverSessionMedia) setFormatSSRCs(sf *serverSessionFormat, layers map[uint32]LayerInfo) {
+	sm.ssrcMutex.Lock()
+	defer sm.ssrcMutex.Unlock()
+
+	for ssrc := range layers {
+		sm.ssrcFormats[ssrc] = sf
+	}
+}
+
+// formatForSSRC returns the serverSessionFormat explicitly registered for
+// ssrc via SetSSRCs, or nil if none was.
+func (sm *serverSessionMedia) formatForSSRC(ssrc uint32) *serverSessionFormat {
+	sm.ssrcMutex.RLock()
+	defer sm.ssrcMutex.RUnlock()
+	return sm.ssrcFormats[ssrc]
+}
+
+// setFormatRTXSSRCs is called by serverSessionFormat.SetRTXSSRCs to
+// record the RTX-SSRC -> primary-SSRC pairs in mapping.
+func (sm *serverSessionMedia) setFormatRTXSSRCs(mapping map[uint32]uint32) {
+	sm.ssrcMutex.Lock()
+	defer sm.ssrcMutex.Unlock()
+
+	for rtxSSRC, primarySSRC := range mapping {
+		sm.rtxSSRCs[rtxSSRC] = primarySSRC
+	}
+}
+
+// primarySSRCForRTX returns the primary SSRC registered for rtxSSRC via
+// SetRTXSSRCs, or false if none was.
+func (sm *serverSessionMedia) primarySSRCForRTX(rtxSSRC uint32) (uint32, bool) {
+	sm.ssrcMutex.RLock()
+	defer sm.ssrcMutex.RUnlock()
+	primarySSRC, ok := sm.rtxSSRCs[rtxSSRC]
+	return primarySSRC, ok
+}
+
+// payloadTypeOf returns the payload type sf is registered under in
+// sm.formats, so callers that resolved sf via an SSRC override can still
+// reach the jitter buffer that was set up for that payload type.
+func (sm *serverSessionMedia) payloadTypeOf(sf *serverSessionFormat) (uint8, bool) {
+	pt, ok := sm.formatPTs[sf]
+	return pt, ok
+}
+
+// resolveRecordFormat determines which serverSessionFormat pkt belongs to
+// on the record path, decapsulating it in place if it arrived as an RTX
+// packet (RFC 4588) and honoring any SSRC override registered through
+// serverSessionFormat.SetSSRCs. It returns the format to deliver to and
+// the payload type under which to look up its jitter buffer. ok is false
+// if pkt could not be resolved at all, in which case the decode error has
+// already been reported.
+func (sm *serverSessionMedia) resolveRecordFormat(pkt *rtp.Packet) (forma *serverSessionFormat, routingPT uint8, ok bool) {
+	forma, ok = sm.formats[pkt.PayloadType]
+	if !ok {
+		sm.ss.onDecodeError(liberrors.ErrServerRTPPacketUnknownPayloadType{PayloadType: pkt.PayloadType})
+		return nil, 0, false
+	}
+	routingPT = pkt.PayloadType
+
+	if origPT, isRTX := sm.rtxFormats[pkt.PayloadType]; isRTX {
+		base, baseOK := sm.formats[origPT]
+		if !baseOK {
+			sm.ss.onDecodeError(liberrors.ErrServerRTPPacketUnknownPayloadType{PayloadType: origPT})
+			return nil, 0, false
+		}
+
+		if err := decapsulateRTX(pkt, origPT); err != nil {
+			sm.ss.onDecodeError(err)
+			return nil, 0, false
+		}
+
+		// decapsulateRTX never touches pkt.SSRC, since RFC 4588 keeps the
+		// RTX stream on its own SSRC; rewrite it to the primary SSRC it
+		// retransmits (if one was registered via SetRTXSSRCs) before the
+		// SetSSRCs-based lookup below, or a simulcast layer combined with
+		// RTX would never be found by it.
+		if primarySSRC, ok := sm.primarySSRCForRTX(pkt.SSRC); ok {
+			pkt.SSRC = primarySSRC
+		}
+
+		forma = base
+		routingPT = origPT
+	}
+
+	if sf := sm.formatForSSRC(pkt.SSRC); sf != nil {
+		forma = sf
+		if pt, ptOK := sm.payloadTypeOf(sf); ptOK {
+			routingPT = pt
+		}
+	}
+
+	return forma, routingPT, true
+}
+
 func (sm *serverSessionMedia) writePacketRTPInQueueUDP(payload []byte) {
+	if sm.srtpContext != nil {
+		var err error
+		payload, err = sm.srtpContext.encryptRTP(payload)
+		if err != nil {
+			return
+		}
+	}
+
 	atomic.AddUint64(sm.ss.bytesSent, uint64(len(payload)))
 	sm.ss.s.udpRTPListener.write(payload, sm.udpRTPWriteAddr) //nolint:errcheck
 }
 
 func (sm *serverSessionMedia) writePacketRTCPInQueueUDP(payload []byte) {
+	if sm.srtpContext != nil {
+		var err error
+		payload, err = sm.srtpContext.encryptRTCP(payload)
+		if err != nil {
+			return
+		}
+	}
+
 	atomic.AddUint64(sm.ss.bytesSent, uint64(len(payload)))
 	sm.ss.s.udpRTCPListener.write(payload, sm.udpRTCPWriteAddr) //nolint:errcheck
 }
 
 func (sm *serverSessionMedia) writePacketRTPInQueueTCP(payload []byte) {
+	if sm.srtpContext != nil {
+		var err error
+		payload, err = sm.srtpContext.encryptRTP(payload)
+		if err != nil {
+			return
+		}
+	}
+
 	atomic.AddUint64(sm.ss.bytesSent, uint64(len(payload)))
 	sm.tcpRTPFrame.Payload = payload
 	sm.ss.tcpConn.nconn.SetWriteDeadline(time.Now().Add(sm.ss.s.WriteTimeout))
@@ -136,13 +348,26 @@ func (sm *serverSessionMedia) writePacketRTPInQueueTCP(payload []byte) {
 }
 
 func (sm *serverSessionMedia) writePacketRTCPInQueueTCP(payload []byte) {
+	if sm.srtpContext != nil {
+		var err error
+		payload, err = sm.srtpContext.encryptRTCP(payload)
+		if err != nil {
+			return
+		}
+	}
+
 	atomic.AddUint64(sm.ss.bytesSent, uint64(len(payload)))
 	sm.tcpRTCPFrame.Payload = payload
 	sm.ss.tcpConn.nconn.SetWriteDeadline(time.Now().Add(sm.ss.s.WriteTimeout))
 	sm.ss.tcpConn.conn.WriteInterleavedFrame(sm.tcpRTCPFrame, sm.tcpBuffer) //nolint:errcheck
 }
 
-func (sm *serverSessionMedia) writePacketRTP(payload []byte) error {
+// writePacketRTPRaw queues an already-marshalled RTP packet for
+// transmission, bypassing the write interceptor chain. Every send path
+// must go through writePacketRTP instead, so that registered interceptors
+// (NACK caching, pacing, ...) actually see outgoing traffic; this is kept
+// unexported-internal and only ever called by writePacketRTP itself.
+func (sm *serverSessionMedia) writePacketRTPRaw(payload []byte) error {
 	ok := sm.ss.writer.push(func() {
 		sm.writePacketRTPInQueue(payload)
 	})
@@ -153,7 +378,8 @@ func (sm *serverSessionMedia) writePacketRTP(payload []byte) error {
 	return nil
 }
 
-func (sm *serverSessionMedia) writePacketRTCP(payload []byte) error {
+// writePacketRTCPRaw is the RTCP equivalent of writePacketRTPRaw.
+func (sm *serverSessionMedia) writePacketRTCPRaw(payload []byte) error {
 	ok := sm.ss.writer.push(func() {
 		sm.writePacketRTCPInQueue(payload)
 	})
@@ -164,6 +390,44 @@ func (sm *serverSessionMedia) writePacketRTCP(payload []byte) error {
 	return nil
 }
 
+// writePacketRTP runs pkt through the registered RTP write interceptors
+// (NACK caching, pacing, ...) before marshalling and queuing it for
+// transmission. This is the only entry point for sending an RTP packet on
+// this media, so every write - not just internal NACK retransmits - is
+// subject to the interceptor chain. Interceptors that return nil drop the
+// packet silently.
+func (sm *serverSessionMedia) writePacketRTP(pkt *rtp.Packet) error {
+	pkt = sm.interceptors.applyRTPWrite(pkt)
+	if pkt == nil {
+		return nil
+	}
+
+	buf, err := pkt.Marshal()
+	if err != nil {
+		return err
+	}
+
+	return sm.writePacketRTPRaw(buf)
+}
+
+// writePacketRTCP is the RTCP equivalent of writePacketRTP: it runs pkt
+// through the registered RTCP write interceptors before marshalling and
+// queuing it, and is the only entry point for sending an RTCP packet on
+// this media.
+func (sm *serverSessionMedia) writePacketRTCP(pkt rtcp.Packet) error {
+	pkt = sm.interceptors.applyRTCPWrite(pkt)
+	if pkt == nil {
+		return nil
+	}
+
+	buf, err := pkt.Marshal()
+	if err != nil {
+		return err
+	}
+
+	return sm.writePacketRTCPRaw(buf)
+}
+
 func (sm *serverSessionMedia) readRTCPUDPPlay(payload []byte) {
 	plen := len(payload)
 
@@ -174,6 +438,15 @@ func (sm *serverSessionMedia) readRTCPUDPPlay(payload []byte) {
 		return
 	}
 
+	if sm.srtpContext != nil {
+		var err error
+		payload, err = sm.srtpContext.decryptRTCP(payload)
+		if err != nil {
+			sm.ss.onDecodeError(err)
+			return
+		}
+	}
+
 	packets, err := rtcp.Unmarshal(payload)
 	if err != nil {
 		sm.ss.onDecodeError(err)
@@ -184,6 +457,12 @@ func (sm *serverSessionMedia) readRTCPUDPPlay(payload []byte) {
 	atomic.StoreInt64(sm.ss.udpLastPacketTime, now.Unix())
 
 	for _, pkt := range packets {
+		pkt = sm.interceptors.applyRTCPRead(pkt)
+		if pkt == nil {
+			continue
+		}
+
+		sm.processBandwidthFeedback(pkt)
 		sm.onPacketRTCP(pkt)
 	}
 }
@@ -198,6 +477,15 @@ func (sm *serverSessionMedia) readRTPUDPRecord(payload []byte) {
 		return
 	}
 
+	if sm.srtpContext != nil {
+		var err error
+		payload, err = sm.srtpContext.decryptRTP(payload)
+		if err != nil {
+			sm.ss.onDecodeError(err)
+			return
+		}
+	}
+
 	pkt := &rtp.Packet{}
 	err := pkt.Unmarshal(payload)
 	if err != nil {
@@ -205,15 +493,24 @@ func (sm *serverSessionMedia) readRTPUDPRecord(payload []byte) {
 		return
 	}
 
-	forma, ok := sm.formats[pkt.PayloadType]
+	pkt = sm.interceptors.applyRTPRead(pkt)
+	if pkt == nil {
+		return
+	}
+
+	forma, routingPT, ok := sm.resolveRecordFormat(pkt)
 	if !ok {
-		sm.ss.onDecodeError(liberrors.ErrServerRTPPacketUnknownPayloadType{PayloadType: pkt.PayloadType})
 		return
 	}
 
 	now := sm.ss.s.timeNow()
 	atomic.StoreInt64(sm.ss.udpLastPacketTime, now.Unix())
 
+	if jb, ok := sm.jitterBuffers[routingPT]; ok {
+		jb.push(pkt, now)
+		return
+	}
+
 	forma.readRTPUDP(pkt, now)
 }
 
@@ -227,6 +524,15 @@ func (sm *serverSessionMedia) readRTCPUDPRecord(payload []byte) {
 		return
 	}
 
+	if sm.srtpContext != nil {
+		var err error
+		payload, err = sm.srtpContext.decryptRTCP(payload)
+		if err != nil {
+			sm.ss.onDecodeError(err)
+			return
+		}
+	}
+
 	packets, err := rtcp.Unmarshal(payload)
 	if err != nil {
 		sm.ss.onDecodeError(err)
@@ -244,6 +550,11 @@ func (sm *serverSessionMedia) readRTCPUDPRecord(payload []byte) {
 			}
 		}
 
+		pkt = sm.interceptors.applyRTCPRead(pkt)
+		if pkt == nil {
+			continue
+		}
+
 		sm.onPacketRTCP(pkt)
 	}
 }
@@ -257,6 +568,15 @@ func (sm *serverSessionMedia) readRTCPTCPPlay(payload []byte) {
 		return
 	}
 
+	if sm.srtpContext != nil {
+		var err error
+		payload, err = sm.srtpContext.decryptRTCP(payload)
+		if err != nil {
+			sm.ss.onDecodeError(err)
+			return
+		}
+	}
+
 	packets, err := rtcp.Unmarshal(payload)
 	if err != nil {
 		sm.ss.onDecodeError(err)
@@ -264,11 +584,26 @@ func (sm *serverSessionMedia) readRTCPTCPPlay(payload []byte) {
 	}
 
 	for _, pkt := range packets {
+		pkt = sm.interceptors.applyRTCPRead(pkt)
+		if pkt == nil {
+			continue
+		}
+
+		sm.processBandwidthFeedback(pkt)
 		sm.onPacketRTCP(pkt)
 	}
 }
 
 func (sm *serverSessionMedia) readRTPTCPRecord(payload []byte) {
+	if sm.srtpContext != nil {
+		var err error
+		payload, err = sm.srtpContext.decryptRTP(payload)
+		if err != nil {
+			sm.ss.onDecodeError(err)
+			return
+		}
+	}
+
 	pkt := &rtp.Packet{}
 	err := pkt.Unmarshal(payload)
 	if err != nil {
@@ -276,9 +611,18 @@ func (sm *serverSessionMedia) readRTPTCPRecord(payload []byte) {
 		return
 	}
 
-	forma, ok := sm.formats[pkt.PayloadType]
+	pkt = sm.interceptors.applyRTPRead(pkt)
+	if pkt == nil {
+		return
+	}
+
+	forma, routingPT, ok := sm.resolveRecordFormat(pkt)
 	if !ok {
-		sm.ss.onDecodeError(liberrors.ErrServerRTPPacketUnknownPayloadType{PayloadType: pkt.PayloadType})
+		return
+	}
+
+	if jb, ok := sm.jitterBuffers[routingPT]; ok {
+		jb.push(pkt, sm.ss.s.timeNow())
 		return
 	}
 
@@ -291,6 +635,15 @@ func (sm *serverSessionMedia) readRTCPTCPRecord(payload []byte) {
 		return
 	}
 
+	if sm.srtpContext != nil {
+		var err error
+		payload, err = sm.srtpContext.decryptRTCP(payload)
+		if err != nil {
+			sm.ss.onDecodeError(err)
+			return
+		}
+	}
+
 	packets, err := rtcp.Unmarshal(payload)
 	if err != nil {
 		sm.ss.onDecodeError(err)
@@ -307,6 +660,11 @@ func (sm *serverSessionMedia) readRTCPTCPRecord(payload []byte) {
 			}
 		}
 
+		pkt = sm.interceptors.applyRTCPRead(pkt)
+		if pkt == nil {
+			continue
+		}
+
 		sm.onPacketRTCP(pkt)
 	}
-}
\ No newline at end of file
+}
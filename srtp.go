@@ -0,0 +1,97 @@
+package gortsplib
+
+import (
+	"github.com/pion/rtp"
+	srtp "github.com/pion/srtp/v2"
+
+	"github.com/bluenviron/gortsplib/v4/pkg/description"
+)
+
+// TransportSecure is like TransportUDP but carries RTP/RTCP wrapped in
+// SRTP/SRTCP (RTP/SAVP, RTP/SAVPF), so a camera can be reached over an
+// untrusted network. A TransportSecure media always uses UDP framing; the
+// TCP interleaved paths encrypt and decrypt too, but only on a TransportTCP
+// media that SetSRTPContext was called on.
+const TransportSecure Transport = 3
+
+// SRTPContext wraps the keys negotiated for a secure session (via MIKEY or
+// a SETUP `KeyMgmt` header) and encrypts/decrypts RTP and RTCP packets,
+// into newly allocated buffers, before they reach the wire or after they
+// come off it.
+type SRTPContext struct {
+	rtp  *srtp.Context
+	rtcp *srtp.Context
+}
+
+// NewSRTPContext builds a SRTPContext from a master key and salt, as
+// negotiated out-of-band (MIKEY or the `KeyMgmt` SETUP header) for the
+// given protection profile.
+func NewSRTPContext(masterKey, masterSalt []byte, profile srtp.ProtectionProfile) (*SRTPContext, error) {
+	rtpCtx, err := srtp.CreateContext(masterKey, masterSalt, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	rtcpCtx, err := srtp.CreateContext(masterKey, masterSalt, profile)
+	if err != nil {
+		return nil, err
+	}
+
+	return &SRTPContext{rtp: rtpCtx, rtcp: rtcpCtx}, nil
+}
+
+func (c *SRTPContext) encryptRTP(payload []byte) ([]byte, error) {
+	var header rtp.Header
+	n, err := header.Unmarshal(payload)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.rtp.EncryptRTP(nil, &header, payload[n:])
+}
+
+func (c *SRTPContext) decryptRTP(encrypted []byte) ([]byte, error) {
+	var header rtp.Header
+	_, err := header.Unmarshal(encrypted)
+	if err != nil {
+		return nil, err
+	}
+
+	return c.rtp.DecryptRTP(nil, encrypted, &header)
+}
+
+func (c *SRTPContext) encryptRTCP(payload []byte) ([]byte, error) {
+	return c.rtcp.EncryptRTCP(nil, payload, nil)
+}
+
+func (c *SRTPContext) decryptRTCP(encrypted []byte) ([]byte, error) {
+	return c.rtcp.DecryptRTCP(nil, encrypted, nil)
+}
+
+// SRTPContext returns the secure-transport keys in use for this media, or
+// nil if the session was set up with a non-secure transport.
+func (sm *serverSessionMedia) SRTPContext() *SRTPContext {
+	return sm.srtpContext
+}
+
+// SetSRTPContext assigns the keys a TransportSecure media encrypts and
+// decrypts its traffic with. It must be called before the session's
+// RECORD/PLAY response is sent (i.e. before start() wires up reads and
+// writes), once the keys have been negotiated out-of-band (MIKEY or the
+// SETUP `KeyMgmt` header) - this package doesn't parse either itself.
+// writePacketRTPInQueueUDP/TCP and readRTPUDP/TCPRecord (and their RTCP
+// equivalents) already gate encryption/decryption on sm.srtpContext being
+// non-nil; this is what actually arms that gate.
+func (sm *serverSessionMedia) SetSRTPContext(ctx *SRTPContext) {
+	sm.srtpContext = ctx
+}
+
+// SetSRTPContext is the ServerSession-level equivalent of
+// serverSessionMedia.SetSRTPContext, for the given media.
+func (ss *ServerSession) SetSRTPContext(medi *description.Media, ctx *SRTPContext) {
+	sm := ss.medias[medi]
+	if sm == nil {
+		return
+	}
+	sm.SetSRTPContext(ctx)
+}
@@ -0,0 +1,100 @@
+package gortsplib
+
+import (
+	"testing"
+
+	"github.com/pion/rtp"
+	srtp "github.com/pion/srtp/v2"
+)
+
+// TestSRTPContextRoundTrip encrypts an RTP packet with one SRTPContext and
+// decrypts it with an independently-built one sharing the same key/salt,
+// mirroring exactly what writePacketRTPInQueueUDP and readRTPUDPRecord do
+// on either end of a TransportSecure session (see SetSRTPContext). A full
+// session-level round trip would additionally need to build a ServerSession
+// and description.Media, neither of which is defined in this package - both
+// come from elsewhere in gortsplib and aren't present in this tree.
+func TestSRTPContextRoundTrip(t *testing.T) {
+	masterKey := make([]byte, 16)
+	masterSalt := make([]byte, 14)
+	for i := range masterKey {
+		masterKey[i] = byte(i)
+	}
+	for i := range masterSalt {
+		masterSalt[i] = byte(i + 1)
+	}
+
+	sender, err := NewSRTPContext(masterKey, masterSalt, srtp.ProtectionProfileAes128CmHmacSha1_80)
+	if err != nil {
+		t.Fatalf("NewSRTPContext (sender): %v", err)
+	}
+	receiver, err := NewSRTPContext(masterKey, masterSalt, srtp.ProtectionProfileAes128CmHmacSha1_80)
+	if err != nil {
+		t.Fatalf("NewSRTPContext (receiver): %v", err)
+	}
+
+	pkt := &rtp.Packet{
+		Header: rtp.Header{
+			SequenceNumber: 1234,
+			Timestamp:      90000,
+			SSRC:           0xdeadbeef,
+		},
+		Payload: []byte("hello from the publisher"),
+	}
+	plain, err := pkt.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal: %v", err)
+	}
+
+	encrypted, err := sender.encryptRTP(plain)
+	if err != nil {
+		t.Fatalf("encryptRTP: %v", err)
+	}
+
+	decrypted, err := receiver.decryptRTP(encrypted)
+	if err != nil {
+		t.Fatalf("decryptRTP: %v", err)
+	}
+
+	var out rtp.Packet
+	if err := out.Unmarshal(decrypted); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+
+	if out.SequenceNumber != pkt.SequenceNumber || string(out.Payload) != string(pkt.Payload) {
+		t.Fatalf("got %+v, want %+v", out, pkt)
+	}
+}
+
+// TestSRTPContextRoundTripRTCP is the RTCP equivalent of
+// TestSRTPContextRoundTrip.
+func TestSRTPContextRoundTripRTCP(t *testing.T) {
+	masterKey := make([]byte, 16)
+	masterSalt := make([]byte, 14)
+
+	sender, err := NewSRTPContext(masterKey, masterSalt, srtp.ProtectionProfileAes128CmHmacSha1_80)
+	if err != nil {
+		t.Fatalf("NewSRTPContext (sender): %v", err)
+	}
+	receiver, err := NewSRTPContext(masterKey, masterSalt, srtp.ProtectionProfileAes128CmHmacSha1_80)
+	if err != nil {
+		t.Fatalf("NewSRTPContext (receiver): %v", err)
+	}
+
+	// a minimal, well-formed RTCP receiver report: V=2,P=0,RC=0, PT=201 (RR), length=1
+	plain := []byte{0x80, 0xc9, 0x00, 0x01, 0x01, 0x02, 0x03, 0x04}
+
+	encrypted, err := sender.encryptRTCP(plain)
+	if err != nil {
+		t.Fatalf("encryptRTCP: %v", err)
+	}
+
+	decrypted, err := receiver.decryptRTCP(encrypted)
+	if err != nil {
+		t.Fatalf("decryptRTCP: %v", err)
+	}
+
+	if string(decrypted) != string(plain) {
+		t.Fatalf("got %v, want %v", decrypted, plain)
+	}
+}